@@ -2,30 +2,168 @@ package main
 
 import (
 	"fmt"
+	"io"
 	"io/ioutil"
+	"math"
 	"strings"
 )
 
-// built-in functions, [name]func{implementation}
-var builtins = map[string]func(*Stack, params, *RuntimeEnv){
-	// strlen - returns the length of a string
-	"strlen": func(s *Stack, p params, env *RuntimeEnv) {
-		s.Push(ScopeEntry{TypNumber, len(p[0].Value.(string))})
-	},
+// AssertionError is raised by the assertion builtins when a check fails.
+type AssertionError struct {
+	Message string
+}
+
+func (e AssertionError) Error() string {
+	return e.Message
+}
+
+// assertMessage returns the optional message argument at index i of p, or
+// def when it is absent.
+func assertMessage(p params, i int, def string) string {
+	if len(p) > i {
+		if p[i].DataType != TypString {
+			panic("assertion message must be a string")
+		}
+		return p[i].Value.(string)
+	}
+	return def
+}
+
+// formatArgs formats the arguments p of the builtin name, a format string
+// followed by the values it formats.
+func formatArgs(name string, p params, env *RuntimeEnv) string {
+	if len(p) < 1 || p[0].DataType != TypString {
+		panic(name + " expects a format string")
+	}
+	str, err := sprintf(env, p[0].Value.(string), p[1:])
+	if err != nil {
+		panic(name + ": " + err.Error())
+	}
+	return str
+}
+
+// expectArgs panics with msg unless p begins with values of the given types.
+func expectArgs(p params, msg string, types ...DataType) {
+	if len(p) < len(types) {
+		panic(msg)
+	}
+	for i, t := range types {
+		if p[i].DataType != t {
+			panic(msg)
+		}
+	}
+}
 
+// intArgs panics with msg unless the values p[i], for each i in indexes,
+// are whole numbers that fit in 32 bits, and returns them.
+func intArgs(p params, msg string, indexes ...int) []int {
+	ints := make([]int, len(indexes))
+	for n, i := range indexes {
+		if p[i].DataType != TypNumber {
+			panic(msg)
+		}
+		v, ok := p[i].Value.(int64)
+		if !ok || v < math.MinInt32 || v > math.MaxInt32 {
+			panic(msg)
+		}
+		ints[n] = int(v)
+	}
+	return ints
+}
+
+// builtin is a function implemented by the interpreter, which may only be
+// called by programs whose environment grants it caps.
+type builtin struct {
+	caps Capability
+	fn   func(*Stack, params, *RuntimeEnv)
+}
+
+// built-in functions, [name]{capabilities, implementation}
+var builtins = map[string]builtin{
 	// write - prints a value
-	"write": func(s *Stack, p params, env *RuntimeEnv) {
+	"write": {CapStdio, func(s *Stack, p params, env *RuntimeEnv) {
 		for i := range p {
-			fmt.Fprint(env.stdout, p[i].Value)
+			fmt.Fprint(env.stdout, castStr(env, p[i]))
 		}
-	},
+	}},
+
+	// format - formats values as directed by a format string
+	"format": {0, func(s *Stack, p params, env *RuntimeEnv) {
+		s.Push(ScopeEntry{TypString, formatArgs("format", p, env)})
+	}},
+
+	// printf - prints values as directed by a format string
+	"printf": {CapStdio, func(s *Stack, p params, env *RuntimeEnv) {
+		fmt.Fprint(env.stdout, formatArgs("printf", p, env))
+	}},
 
 	// read - read a string
-	"read": func(s *Stack, p params, env *RuntimeEnv) {
-		b, err := ioutil.ReadAll(env.stdin)
+	"read": {CapStdio, func(s *Stack, p params, env *RuntimeEnv) {
+		in := env.stdin
+		if env.maxSize > 0 {
+			// read no more than enough to tell the input is too long
+			in = io.LimitReader(in, int64(env.maxSize)+1)
+		}
+		b, err := env.readAll(in)
 		if err != nil {
 			panic(err)
 		}
-		s.Push(ScopeEntry{TypString, strings.TrimRight(string(b), "\n")})
-	},
+		str := strings.TrimRight(string(b), "\n")
+		env.checkSize(len(str))
+		s.Push(ScopeEntry{TypString, str})
+	}},
+
+	// readfile - reads the contents of a file
+	"readfile": {CapFSRead, func(s *Stack, p params, env *RuntimeEnv) {
+		if len(p) < 1 || p[0].DataType != TypString {
+			panic("readfile expects a path")
+		}
+		path := p[0].Value.(string)
+		env.checkPath("readfile", CapFSRead, path)
+		b, err := ioutil.ReadFile(path)
+		if err != nil {
+			panic(err)
+		}
+		env.checkSize(len(b))
+		s.Push(ScopeEntry{TypString, string(b)})
+	}},
+
+	// writefile - replaces the contents of a file with a string
+	"writefile": {CapFSWrite, func(s *Stack, p params, env *RuntimeEnv) {
+		if len(p) < 2 || p[0].DataType != TypString || p[1].DataType != TypString {
+			panic("writefile expects a path and a string")
+		}
+		path := p[0].Value.(string)
+		env.checkPath("writefile", CapFSWrite, path)
+		if err := ioutil.WriteFile(path, []byte(p[1].Value.(string)), 0644); err != nil {
+			panic(err)
+		}
+	}},
+
+	// assert - fails unless a condition is true
+	"assert": {0, func(s *Stack, p params, env *RuntimeEnv) {
+		if len(p) < 1 || p[0].DataType != TypBool {
+			panic("assert expects a bool condition")
+		}
+		if !p[0].Value.(bool) {
+			panic(AssertionError{assertMessage(p, 1, "assertion failed")})
+		}
+	}},
+
+	// assert_eq - fails unless two values have the same type and value
+	"assert_eq": {0, func(s *Stack, p params, env *RuntimeEnv) {
+		if len(p) < 2 {
+			panic("assert_eq expects two values")
+		}
+		if !valuesEqual(p[0], p[1]) {
+			panic(AssertionError{assertMessage(p, 2, fmt.Sprintf(
+				"values are not equal: %s ~= %s",
+				formatValue(p[0]), formatValue(p[1])))})
+		}
+	}},
+
+	// fail - fails unconditionally
+	"fail": {0, func(s *Stack, p params, env *RuntimeEnv) {
+		panic(AssertionError{assertMessage(p, 0, "failed")})
+	}},
 }
@@ -0,0 +1,206 @@
+package main
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+// listArg returns the list argument of the builtin, panicking with msg
+// unless p[i] is a list.
+func listArg(p params, i int, msg string) []ScopeEntry {
+	if len(p) <= i || p[i].DataType != TypList {
+		panic(msg)
+	}
+	return p[i].Value.([]ScopeEntry)
+}
+
+// mapKeyArgs returns the map argument of the builtin and the key that
+// follows it, panicking with msg unless they are a map and a string.
+func mapKeyArgs(p params, msg string) (*Map, string) {
+	expectArgs(p, msg, TypMap, TypString)
+	return p[0].Value.(*Map), p[1].Value.(string)
+}
+
+// container built-in functions, which return new lists and maps rather
+// than changing those they are given
+var containerBuiltins = map[string]builtin{
+	// list - returns a list of its arguments
+	"list": {0, func(s *Stack, p params, env *RuntimeEnv) {
+		env.checkSize(len(p))
+		s.Push(ScopeEntry{TypList, append([]ScopeEntry{}, p...)})
+	}},
+
+	// map - returns a map of its arguments, alternating keys and values
+	"map": {0, func(s *Stack, p params, env *RuntimeEnv) {
+		msg := "map expects pairs of keys and values"
+		if len(p)%2 != 0 {
+			panic(msg)
+		}
+		m := NewMap()
+		for i := 0; i < len(p); i += 2 {
+			if p[i].DataType != TypString {
+				panic(msg)
+			}
+			m.Set(p[i].Value.(string), p[i+1])
+		}
+		env.checkSize(m.Len())
+		s.Push(ScopeEntry{TypMap, m})
+	}},
+
+	// null - returns null, the absence of a value
+	"null": {0, func(s *Stack, p params, env *RuntimeEnv) {
+		s.Push(ScopeEntry{TypNull, nil})
+	}},
+
+	// typeof - returns the name of a value's type
+	"typeof": {0, func(s *Stack, p params, env *RuntimeEnv) {
+		if len(p) != 1 {
+			panic("typeof expects a value")
+		}
+		s.Push(ScopeEntry{TypString, dataTypeName(p[0].DataType)})
+	}},
+
+	// len - returns the number of characters in a string, or of elements
+	// in a list or map
+	"len": {0, func(s *Stack, p params, env *RuntimeEnv) {
+		if len(p) < 1 {
+			panic("len expects a string, list or map")
+		}
+		var n int
+		switch p[0].DataType {
+		case TypString:
+			n = utf8.RuneCountInString(p[0].Value.(string))
+		case TypList:
+			n = len(p[0].Value.([]ScopeEntry))
+		case TypMap:
+			n = p[0].Value.(*Map).Len()
+		default:
+			panic("len expects a string, list or map")
+		}
+		s.Push(ScopeEntry{TypNumber, int64(n)})
+	}},
+
+	// append - returns a list with values added to its end
+	"append": {0, func(s *Stack, p params, env *RuntimeEnv) {
+		list := listArg(p, 0, "append expects a list and values")
+		env.checkSize(len(list) + len(p) - 1)
+		list = append(list[:len(list):len(list)], p[1:]...)
+		s.Push(ScopeEntry{TypList, list})
+	}},
+
+	// put - returns a map with a key set to a value
+	"put": {0, func(s *Stack, p params, env *RuntimeEnv) {
+		msg := "put expects a map, a key and a value"
+		m, key := mapKeyArgs(p, msg)
+		if len(p) < 3 {
+			panic(msg)
+		}
+		m = m.Copy()
+		m.Set(key, p[2])
+		env.checkSize(m.Len())
+		s.Push(ScopeEntry{TypMap, m})
+	}},
+
+	// delete - returns a map without a key
+	"delete": {0, func(s *Stack, p params, env *RuntimeEnv) {
+		m, key := mapKeyArgs(p, "delete expects a map and a key")
+		m = m.Copy()
+		m.Delete(key)
+		s.Push(ScopeEntry{TypMap, m})
+	}},
+
+	// keys - returns a list of the keys of a map, in the order they were
+	// added
+	"keys": {0, func(s *Stack, p params, env *RuntimeEnv) {
+		expectArgs(p, "keys expects a map", TypMap)
+		keys := []ScopeEntry{}
+		for _, k := range p[0].Value.(*Map).Keys() {
+			keys = append(keys, ScopeEntry{TypString, k})
+		}
+		s.Push(ScopeEntry{TypList, keys})
+	}},
+
+	// values - returns a list of the values of a map, in the order their
+	// keys were added
+	"values": {0, func(s *Stack, p params, env *RuntimeEnv) {
+		expectArgs(p, "values expects a map", TypMap)
+		m := p[0].Value.(*Map)
+		vals := []ScopeEntry{}
+		for _, k := range m.Keys() {
+			v, _ := m.Get(k)
+			vals = append(vals, v)
+		}
+		s.Push(ScopeEntry{TypList, vals})
+	}},
+
+	// has - reports whether a map has a key, or a list holds a value
+	"has": {0, func(s *Stack, p params, env *RuntimeEnv) {
+		msg := "has expects a list or map and a value"
+		if len(p) < 2 {
+			panic(msg)
+		}
+		switch p[0].DataType {
+		case TypMap:
+			m, key := mapKeyArgs(p, "has expects a map and a key")
+			_, ok := m.Get(key)
+			s.Push(ScopeEntry{TypBool, ok})
+		case TypList:
+			found := false
+			for _, e := range p[0].Value.([]ScopeEntry) {
+				if valuesEqual(e, p[1]) {
+					found = true
+					break
+				}
+			}
+			s.Push(ScopeEntry{TypBool, found})
+		default:
+			panic(msg)
+		}
+	}},
+
+	// json_encode - returns a value encoded as JSON, on one line or
+	// indented by a number of spaces or a string
+	"json_encode": {0, func(s *Stack, p params, env *RuntimeEnv) {
+		msg := "json_encode expects a value and an optional indent"
+		if len(p) < 1 {
+			panic(msg)
+		}
+		indent := ""
+		if len(p) > 1 {
+			switch p[1].DataType {
+			case TypString:
+				indent = p[1].Value.(string)
+			case TypNumber:
+				n := intArgs(p, msg, 1)[0]
+				if n < 0 {
+					panic(msg)
+				}
+				env.checkSize(n)
+				indent = strings.Repeat(" ", n)
+			default:
+				panic(msg)
+			}
+		}
+		str, err := encodeJSON(env, p[0], indent)
+		if err != nil {
+			panic("json_encode: " + err.Error())
+		}
+		s.Push(ScopeEntry{TypString, str})
+	}},
+
+	// json_decode - returns the value of a JSON document
+	"json_decode": {0, func(s *Stack, p params, env *RuntimeEnv) {
+		expectArgs(p, "json_decode expects a string", TypString)
+		e, err := decodeJSON(env, p[0].Value.(string))
+		if err != nil {
+			panic("json_decode: " + err.Error())
+		}
+		s.Push(e)
+	}},
+}
+
+func init() {
+	for name, b := range containerBuiltins {
+		builtins[name] = b
+	}
+}
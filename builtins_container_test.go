@@ -0,0 +1,128 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestContainerBuiltins(t *testing.T) {
+	t.Parallel()
+
+	env := testRuntimeEnv("")
+
+	list := func(args ...interface{}) []ScopeEntry {
+		return callBuiltin(env, "list", args...).Value.([]ScopeEntry)
+	}
+
+	t.Run("list", func(t *testing.T) {
+		assert.Equal(t, []ScopeEntry{testEntry(1), testEntry("a")}, list(1, "a"))
+		assert.Equal(t, []ScopeEntry{}, list())
+	})
+
+	t.Run("map", func(t *testing.T) {
+		m := callBuiltin(env, "map", "b", 1, "a", true).Value.(*Map)
+		assert.Equal(t, []string{"b", "a"}, m.Keys())
+		assert.PanicsWithValue(t, "map expects pairs of keys and values", func() {
+			callBuiltin(env, "map", "a")
+		})
+		assert.PanicsWithValue(t, "map expects pairs of keys and values", func() {
+			callBuiltin(env, "map", 1, 2)
+		})
+	})
+
+	t.Run("null and typeof", func(t *testing.T) {
+		assert.Equal(t, ScopeEntry{TypNull, nil}, callBuiltin(env, "null"))
+		assert.Equal(t, "null", callBuiltin(env, "typeof", callBuiltin(env, "null")).Value)
+		assert.Equal(t, "list", callBuiltin(env, "typeof", list()).Value)
+		assert.Equal(t, "map", callBuiltin(env, "typeof", callBuiltin(env, "map")).Value)
+		assert.Equal(t, "num", callBuiltin(env, "typeof", 1).Value)
+		assert.Equal(t, "str", callBuiltin(env, "typeof", "").Value)
+	})
+
+	t.Run("len", func(t *testing.T) {
+		assert.Equal(t, int64(5), callBuiltin(env, "len", "héllo").Value)
+		assert.Equal(t, int64(2), callBuiltin(env, "len", list(1, 2)).Value)
+		assert.Equal(t, int64(1), callBuiltin(env, "len", callBuiltin(env, "map", "a", 1)).Value)
+		assert.PanicsWithValue(t, "len expects a string, list or map", func() {
+			callBuiltin(env, "len", 42)
+		})
+	})
+
+	t.Run("append", func(t *testing.T) {
+		a := list(1)
+		b := callBuiltin(env, "append", a, 2, 3).Value.([]ScopeEntry)
+		c := callBuiltin(env, "append", a, 4).Value.([]ScopeEntry)
+		assert.Equal(t, list(1, 2, 3), b)
+		assert.Equal(t, list(1, 4), c)
+		assert.Equal(t, list(1), a)
+		assert.PanicsWithValue(t, "append expects a list and values", func() {
+			callBuiltin(env, "append", "a", 1)
+		})
+	})
+
+	t.Run("put and delete", func(t *testing.T) {
+		m := callBuiltin(env, "map", "a", 1).Value.(*Map)
+		put := callBuiltin(env, "put", m, "b", 2).Value.(*Map)
+		assert.Equal(t, []string{"a", "b"}, put.Keys())
+		assert.Equal(t, []string{"a"}, m.Keys())
+
+		del := callBuiltin(env, "delete", put, "a").Value.(*Map)
+		assert.Equal(t, []string{"b"}, del.Keys())
+		assert.Equal(t, []string{"a", "b"}, put.Keys())
+
+		assert.PanicsWithValue(t, "put expects a map, a key and a value", func() {
+			callBuiltin(env, "put", m, "b")
+		})
+		assert.PanicsWithValue(t, "delete expects a map and a key", func() {
+			callBuiltin(env, "delete", m, 1)
+		})
+	})
+
+	t.Run("keys and values", func(t *testing.T) {
+		m := callBuiltin(env, "map", "b", 1, "a", 2)
+		assert.Equal(t, list("b", "a"), callBuiltin(env, "keys", m).Value)
+		assert.Equal(t, list(1, 2), callBuiltin(env, "values", m).Value)
+		assert.Equal(t, list(), callBuiltin(env, "keys", callBuiltin(env, "map")).Value)
+	})
+
+	t.Run("has", func(t *testing.T) {
+		m := callBuiltin(env, "map", "a", 1)
+		assert.Equal(t, true, callBuiltin(env, "has", m, "a").Value)
+		assert.Equal(t, false, callBuiltin(env, "has", m, "b").Value)
+		assert.Equal(t, true, callBuiltin(env, "has", list(1, "x"), 1.0).Value)
+		assert.Equal(t, false, callBuiltin(env, "has", list(1, "x"), "y").Value)
+		assert.PanicsWithValue(t, "has expects a map and a key", func() {
+			callBuiltin(env, "has", m, 1)
+		})
+		assert.PanicsWithValue(t, "has expects a list or map and a value", func() {
+			callBuiltin(env, "has", "abc", "a")
+		})
+	})
+
+	t.Run("json_encode", func(t *testing.T) {
+		v := callBuiltin(env, "map", "a", list(1, true), "b", callBuiltin(env, "null"))
+		assert.Equal(t, `{"a":[1,true],"b":null}`, callBuiltin(env, "json_encode", v).Value)
+		assert.Equal(t, "[\n  1\n]", callBuiltin(env, "json_encode", list(1), 2).Value)
+		assert.Equal(t, "[\n\t1\n]", callBuiltin(env, "json_encode", list(1), "\t").Value)
+		assert.Equal(t, `"x"`, callBuiltin(env, "json_encode", "x").Value)
+		assert.PanicsWithValue(t, "json_encode: +Inf cannot be encoded as JSON", func() {
+			callBuiltin(env, "json_encode", callBuiltin(env, "append", list(), callBuiltin(env, "inf")))
+		})
+		assert.PanicsWithValue(t, "json_encode expects a value and an optional indent", func() {
+			callBuiltin(env, "json_encode", list(), -1)
+		})
+	})
+
+	t.Run("json_decode", func(t *testing.T) {
+		e := callBuiltin(env, "json_decode", `{"a": [1, 2.5, "x"], "b": null}`)
+		assert.Equal(t, TypMap, e.DataType)
+		assert.Equal(t, `{"a":[1,2.5,"x"],"b":null}`, callBuiltin(env, "json_encode", e).Value)
+		assert.PanicsWithValue(t, "json_decode: invalid character 'x' looking for beginning of value at offset 4", func() {
+			callBuiltin(env, "json_decode", `[1, x]`)
+		})
+		assert.PanicsWithValue(t, "json_decode expects a string", func() {
+			callBuiltin(env, "json_decode", 1)
+		})
+	})
+}
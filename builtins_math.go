@@ -0,0 +1,218 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"math/big"
+)
+
+// floatArgs returns the n number arguments of the builtin name as floats.
+func floatArgs(name string, p params, n int) []float64 {
+	msg := name + " expects a number"
+	types := make([]DataType, n)
+	for i := range types {
+		types[i] = TypNumber
+	}
+	if n > 1 {
+		msg = name + " expects numbers"
+	}
+	expectArgs(p, msg, types...)
+	floats := make([]float64, n)
+	for i := range floats {
+		floats[i] = toFloat(p[i].Value)
+	}
+	return floats
+}
+
+// floatFunc returns a builtin that applies f to its number argument.
+func floatFunc(name string, f func(float64) float64) builtin {
+	return builtin{0, func(s *Stack, p params, env *RuntimeEnv) {
+		s.Push(ScopeEntry{TypNumber, f(floatArgs(name, p, 1)[0])})
+	}}
+}
+
+// constFunc returns a builtin that returns the number v.
+func constFunc(v float64) builtin {
+	return builtin{0, func(s *Stack, p params, env *RuntimeEnv) {
+		s.Push(ScopeEntry{TypNumber, v})
+	}}
+}
+
+// roundFunc returns a builtin that rounds its argument to a whole value,
+// using f for numbers and mode for decimals. Numbers round to integers,
+// unless they are infinite or NaN.
+func roundFunc(name string, f func(float64) float64, mode RoundingMode) builtin {
+	return builtin{0, func(s *Stack, p params, env *RuntimeEnv) {
+		if len(p) > 0 && p[0].DataType == TypDecimal {
+			s.Push(ScopeEntry{TypDecimal, p[0].Value.(Decimal).Round(0, mode)})
+			return
+		}
+		expectArgs(p, name+" expects a number", TypNumber)
+		v := p[0].Value
+		if x, ok := v.(float64); ok && !math.IsInf(x, 0) && !math.IsNaN(x) {
+			b, _ := new(big.Float).SetFloat64(f(x)).Int(nil)
+			v = normInt(b)
+		}
+		s.Push(ScopeEntry{TypNumber, v})
+	}}
+}
+
+// extremeFunc returns a builtin that returns the argument for which
+// better(c) holds, where c compares it with each other argument. The
+// arguments are all numbers, and any NaN is the result, or all decimals.
+func extremeFunc(name string, better func(int) bool) builtin {
+	return builtin{0, func(s *Stack, p params, env *RuntimeEnv) {
+		msg := name + " expects numbers or decimals"
+		if len(p) < 1 {
+			panic(msg)
+		}
+		best := p[0]
+		for _, e := range p {
+			if e.DataType != best.DataType {
+				panic(msg)
+			}
+			switch e.DataType {
+			case TypNumber:
+				c, ok := numCmp(e.Value, best.Value)
+				if !ok {
+					if v, nan := e.Value.(float64); nan && math.IsNaN(v) {
+						best = e
+					}
+					continue
+				}
+				if better(c) {
+					best = e
+				}
+			case TypDecimal:
+				if better(e.Value.(Decimal).Cmp(best.Value.(Decimal))) {
+					best = e
+				}
+			default:
+				panic(msg)
+			}
+		}
+		s.Push(best)
+	}}
+}
+
+// math built-in functions
+var mathBuiltins = map[string]builtin{
+	// sqrt - returns the square root of a number, exactly if it is a
+	// perfect square
+	"sqrt": {0, func(s *Stack, p params, env *RuntimeEnv) {
+		expectArgs(p, "sqrt expects a number", TypNumber)
+		v := p[0].Value
+		if isInt(v) && toBig(v).Sign() >= 0 {
+			r := new(big.Int).Sqrt(toBig(v))
+			if new(big.Int).Mul(r, r).Cmp(toBig(v)) == 0 {
+				s.Push(ScopeEntry{TypNumber, normInt(r)})
+				return
+			}
+		}
+		s.Push(ScopeEntry{TypNumber, math.Sqrt(toFloat(v))})
+	}},
+
+	// pow - returns a number raised to a power, as the ** operator does
+	"pow": {0, func(s *Stack, p params, env *RuntimeEnv) {
+		expectArgs(p, "pow expects numbers", TypNumber, TypNumber)
+		s.Push(ScopeEntry{TypNumber, numPow(p[0].Value, p[1].Value)})
+	}},
+
+	// exp - returns e raised to a power
+	"exp": floatFunc("exp", math.Exp),
+
+	// log - returns the natural logarithm of a number
+	"log": floatFunc("log", math.Log),
+
+	// floor - rounds down
+	"floor": roundFunc("floor", math.Floor, RoundFloor),
+
+	// ceil - rounds up
+	"ceil": roundFunc("ceil", math.Ceil, RoundCeiling),
+
+	// round - rounds to nearest, with halves away from zero
+	"round": roundFunc("round", math.Round, RoundHalfUp),
+
+	// trunc - rounds toward zero
+	"trunc": roundFunc("trunc", math.Trunc, RoundDown),
+
+	// min - returns the least of its arguments
+	"min": extremeFunc("min", func(c int) bool { return c < 0 }),
+
+	// max - returns the greatest of its arguments
+	"max": extremeFunc("max", func(c int) bool { return c > 0 }),
+
+	// sin - returns the sine of an angle in radians
+	"sin": floatFunc("sin", math.Sin),
+
+	// cos - returns the cosine of an angle in radians
+	"cos": floatFunc("cos", math.Cos),
+
+	// tan - returns the tangent of an angle in radians
+	"tan": floatFunc("tan", math.Tan),
+
+	// atan2 - returns the angle in radians of the point (x, y), given y
+	// and x
+	"atan2": {0, func(s *Stack, p params, env *RuntimeEnv) {
+		f := floatArgs("atan2", p, 2)
+		s.Push(ScopeEntry{TypNumber, math.Atan2(f[0], f[1])})
+	}},
+
+	// pi - returns π
+	"pi": constFunc(math.Pi),
+
+	// e - returns Euler's number
+	"e": constFunc(math.E),
+
+	// inf - returns positive infinity
+	"inf": constFunc(math.Inf(1)),
+
+	// nan - returns NaN, the result of an undefined operation
+	"nan": constFunc(math.NaN()),
+
+	// isnan - reports whether a number is NaN
+	"isnan": {0, func(s *Stack, p params, env *RuntimeEnv) {
+		expectArgs(p, "isnan expects a number", TypNumber)
+		s.Push(ScopeEntry{TypBool, math.IsNaN(toFloat(p[0].Value))})
+	}},
+
+	// isinf - reports whether a number is infinite
+	"isinf": {0, func(s *Stack, p params, env *RuntimeEnv) {
+		expectArgs(p, "isinf expects a number", TypNumber)
+		v, ok := p[0].Value.(float64)
+		s.Push(ScopeEntry{TypBool, ok && math.IsInf(v, 0)})
+	}},
+
+	// decimal_scale - sets the least number of fractional digits in the
+	// quotient of a decimal division, 0 for the default, and optionally how
+	// the quotient is rounded
+	"decimal_scale": {0, func(s *Stack, p params, env *RuntimeEnv) {
+		msg := "decimal_scale expects a scale and an optional rounding mode"
+		expectArgs(p, msg, TypNumber)
+		scale := intArgs(p, msg, 0)[0]
+		if scale < 0 {
+			panic(msg)
+		}
+		env.checkSize(scale)
+		mode, set := RoundHalfEven, len(p) > 1
+		if set {
+			expectArgs(p, msg, TypNumber, TypString)
+			var ok bool
+			if mode, ok = roundingModes[p[1].Value.(string)]; !ok {
+				panic(fmt.Sprintf("decimal_scale: unknown rounding mode %q", p[1].Value))
+			}
+		}
+		if env != nil {
+			env.decScale = scale
+			if set {
+				env.decRounding = mode
+			}
+		}
+	}},
+}
+
+func init() {
+	for name, b := range mathBuiltins {
+		builtins[name] = b
+	}
+}
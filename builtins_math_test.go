@@ -0,0 +1,93 @@
+package main
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMathBuiltins(t *testing.T) {
+	t.Parallel()
+
+	env := testRuntimeEnv("")
+
+	t.Run("sqrt", func(t *testing.T) {
+		assert.Equal(t, int64(12), callBuiltin(env, "sqrt", 144).Value)
+		assert.Equal(t, math.Sqrt2, callBuiltin(env, "sqrt", 2).Value)
+		assert.Equal(t, 1.5, callBuiltin(env, "sqrt", 2.25).Value)
+		assert.Equal(t, int64(4294967296), callBuiltin(env, "sqrt", bigInt("18446744073709551616")).Value)
+		assert.True(t, math.IsNaN(callBuiltin(env, "sqrt", -4).Value.(float64)))
+		assert.PanicsWithValue(t, "sqrt expects a number", func() {
+			callBuiltin(env, "sqrt", "4")
+		})
+	})
+
+	t.Run("pow", func(t *testing.T) {
+		assert.Equal(t, int64(1024), callBuiltin(env, "pow", 2, 10).Value)
+		assert.Equal(t, 0.5, callBuiltin(env, "pow", 2, -1).Value)
+		assert.PanicsWithValue(t, "pow expects numbers", func() {
+			callBuiltin(env, "pow", 2)
+		})
+	})
+
+	t.Run("exp and log", func(t *testing.T) {
+		assert.Equal(t, 1.0, callBuiltin(env, "exp", 0).Value)
+		assert.Equal(t, 1.0, callBuiltin(env, "log", math.E).Value)
+		assert.Equal(t, math.Inf(-1), callBuiltin(env, "log", 0).Value)
+	})
+
+	t.Run("Rounding", func(t *testing.T) {
+		assert.Equal(t, int64(2), callBuiltin(env, "floor", 2.7).Value)
+		assert.Equal(t, int64(-3), callBuiltin(env, "floor", -2.5).Value)
+		assert.Equal(t, int64(3), callBuiltin(env, "ceil", 2.1).Value)
+		assert.Equal(t, int64(3), callBuiltin(env, "round", 2.5).Value)
+		assert.Equal(t, int64(-3), callBuiltin(env, "round", -2.5).Value)
+		assert.Equal(t, int64(-2), callBuiltin(env, "trunc", -2.7).Value)
+		assert.Equal(t, int64(7), callBuiltin(env, "floor", 7).Value)
+		assert.Equal(t, bigInt("100000000000000000000"), callBuiltin(env, "round", 1e20).Value)
+		assert.Equal(t, math.Inf(1), callBuiltin(env, "floor", math.Inf(1)).Value)
+		assert.Equal(t, "-3", callBuiltin(env, "floor", dec("-2.5")).Value.(Decimal).String())
+		assert.Equal(t, "3", callBuiltin(env, "round", dec("2.5")).Value.(Decimal).String())
+		assert.PanicsWithValue(t, "floor expects a number", func() {
+			callBuiltin(env, "floor", "2.5")
+		})
+	})
+
+	t.Run("min and max", func(t *testing.T) {
+		assert.Equal(t, int64(-1), callBuiltin(env, "min", 3, -1, 2).Value)
+		assert.Equal(t, 3.5, callBuiltin(env, "max", 3, 3.5, 2).Value)
+		assert.True(t, math.IsNaN(callBuiltin(env, "max", 1, math.NaN(), 2).Value.(float64)))
+		assert.Equal(t, "1.5", callBuiltin(env, "min", dec("2"), dec("1.5")).Value.(Decimal).String())
+		assert.PanicsWithValue(t, "min expects numbers or decimals", func() {
+			callBuiltin(env, "min")
+		})
+		assert.PanicsWithValue(t, "max expects numbers or decimals", func() {
+			callBuiltin(env, "max", 1, dec("2"))
+		})
+	})
+
+	t.Run("Trigonometry", func(t *testing.T) {
+		assert.Equal(t, 0.0, callBuiltin(env, "sin", 0).Value)
+		assert.Equal(t, 1.0, callBuiltin(env, "cos", 0).Value)
+		assert.Equal(t, 0.0, callBuiltin(env, "tan", 0).Value)
+		assert.Equal(t, math.Pi/2, callBuiltin(env, "atan2", 1, 0).Value)
+		assert.PanicsWithValue(t, "atan2 expects numbers", func() {
+			callBuiltin(env, "atan2", 1)
+		})
+	})
+
+	t.Run("Constants", func(t *testing.T) {
+		assert.Equal(t, math.Pi, callBuiltin(env, "pi").Value)
+		assert.Equal(t, math.E, callBuiltin(env, "e").Value)
+		assert.Equal(t, math.Inf(1), callBuiltin(env, "inf").Value)
+		assert.True(t, math.IsNaN(callBuiltin(env, "nan").Value.(float64)))
+	})
+
+	t.Run("isnan and isinf", func(t *testing.T) {
+		assert.Equal(t, true, callBuiltin(env, "isnan", math.NaN()).Value)
+		assert.Equal(t, false, callBuiltin(env, "isnan", 1).Value)
+		assert.Equal(t, true, callBuiltin(env, "isinf", math.Inf(-1)).Value)
+		assert.Equal(t, false, callBuiltin(env, "isinf", bigInt("18446744073709551616")).Value)
+	})
+}
@@ -0,0 +1,60 @@
+package main
+
+import "os"
+
+// maxExitStatus is the greatest exit status a process can portably report.
+const maxExitStatus = 255
+
+// operating system built-in functions, which give a program its arguments
+// and environment and let it end the process with a status
+var osBuiltins = map[string]builtin{
+	// args - returns a list of the command-line arguments that follow the
+	// program's file
+	"args": {0, func(s *Stack, p params, env *RuntimeEnv) {
+		var args []string
+		if env != nil {
+			args = env.args
+		}
+		s.Push(stringList(args))
+	}},
+
+	// getenv - returns the value of an environment variable, or a default
+	// or the empty string if it is not set
+	"getenv": {CapEnv, func(s *Stack, p params, env *RuntimeEnv) {
+		msg := "getenv expects a name and an optional default"
+		expectArgs(p, msg, TypString)
+		val, ok := os.LookupEnv(p[0].Value.(string))
+		if !ok && len(p) > 1 {
+			expectArgs(p, msg, TypString, TypString)
+			val = p[1].Value.(string)
+		}
+		s.Push(ScopeEntry{TypString, val})
+	}},
+
+	// setenv - sets an environment variable
+	"setenv": {CapEnv, func(s *Stack, p params, env *RuntimeEnv) {
+		expectArgs(p, "setenv expects a name and a value", TypString, TypString)
+		if err := os.Setenv(p[0].Value.(string), p[1].Value.(string)); err != nil {
+			panic("setenv: " + err.Error())
+		}
+	}},
+
+	// exit - ends the program with a status, 0 if none is given
+	"exit": {CapProcess, func(s *Stack, p params, env *RuntimeEnv) {
+		msg := "exit expects a status from 0 to 255"
+		code := 0
+		if len(p) > 0 {
+			code = intArgs(p, msg, 0)[0]
+		}
+		if code < 0 || code > maxExitStatus {
+			panic(msg)
+		}
+		panic(&ExitError{code})
+	}},
+}
+
+func init() {
+	for name, b := range osBuiltins {
+		builtins[name] = b
+	}
+}
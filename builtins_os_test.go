@@ -0,0 +1,68 @@
+package main
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOSBuiltins(t *testing.T) {
+	t.Parallel()
+
+	t.Run("args", func(t *testing.T) {
+		env := testRuntimeEnv("")
+		env.args = []string{"-v", "file.txt"}
+		assert.Equal(t, stringList(env.args).Value, callBuiltin(env, "args").Value)
+		assert.Equal(t, []ScopeEntry{}, callBuiltin(testRuntimeEnv(""), "args").Value)
+		assert.Equal(t, []ScopeEntry{}, callBuiltin(nil, "args").Value)
+	})
+
+	t.Run("getenv and setenv", func(t *testing.T) {
+		const name = "KIWI_TEST_OS_BUILTINS"
+		defer os.Unsetenv(name)
+
+		assert.Equal(t, "", callBuiltin(nil, "getenv", name).Value)
+		assert.Equal(t, "none", callBuiltin(nil, "getenv", name, "none").Value)
+		callBuiltin(nil, "setenv", name, "kiwi")
+		assert.Equal(t, "kiwi", os.Getenv(name))
+		assert.Equal(t, "kiwi", callBuiltin(nil, "getenv", name, "none").Value)
+		callBuiltin(nil, "setenv", name, "")
+		assert.Equal(t, "", callBuiltin(nil, "getenv", name, "none").Value)
+
+		assert.PanicsWithValue(t, "getenv expects a name and an optional default", func() {
+			callBuiltin(nil, "getenv", 1)
+		})
+		assert.PanicsWithValue(t, "getenv expects a name and an optional default", func() {
+			callBuiltin(nil, "getenv", "KIWI_TEST_UNSET", 1)
+		})
+		assert.PanicsWithValue(t, "setenv expects a name and a value", func() {
+			callBuiltin(nil, "setenv", name)
+		})
+		assert.PanicsWithValue(t, "setenv: setenv: invalid argument", func() {
+			callBuiltin(nil, "setenv", "", "x")
+		})
+	})
+
+	t.Run("exit", func(t *testing.T) {
+		exit := func(args ...interface{}) (err interface{}) {
+			defer func() { err = recover() }()
+			callBuiltin(nil, "exit", args...)
+			return nil
+		}
+		assert.Equal(t, &ExitError{0}, exit())
+		assert.Equal(t, &ExitError{255}, exit(255))
+		for _, code := range []interface{}{-1, 256, 1.5, "1"} {
+			assert.PanicsWithValue(t, "exit expects a status from 0 to 255", func() {
+				callBuiltin(nil, "exit", code)
+			})
+		}
+	})
+
+	t.Run("Test capabilities", func(t *testing.T) {
+		assert.Equal(t, CapEnv, builtins["getenv"].caps)
+		assert.Equal(t, CapEnv, builtins["setenv"].caps)
+		assert.Equal(t, Capability(0), builtins["args"].caps)
+		assert.Equal(t, CapProcess, builtins["exit"].caps)
+	})
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"math/big"
+	"math/rand"
+	"time"
+)
+
+// random returns the pseudo-random number generator of env, seeding it from
+// the clock if the program has not been given a seed.
+func (env *RuntimeEnv) random() *rand.Rand {
+	if env.rng == nil {
+		env.rng = rand.New(rand.NewSource(time.Now().UnixNano()))
+	}
+	return env.rng
+}
+
+// pseudo-random built-in functions, whose sequence is fixed by the seed of
+// the environment's generator
+var randBuiltins = map[string]builtin{
+	// seed - seeds the generator, making the values that follow repeatable
+	"seed": {CapRandom, func(s *Stack, p params, env *RuntimeEnv) {
+		expectArgs(p, "seed expects an integer", TypNumber)
+		v, ok := p[0].Value.(int64)
+		if !ok {
+			panic("seed expects an integer")
+		}
+		env.random().Seed(v)
+	}},
+
+	// random - returns a number in the range [0, 1)
+	"random": {CapRandom, func(s *Stack, p params, env *RuntimeEnv) {
+		s.Push(ScopeEntry{TypNumber, env.random().Float64()})
+	}},
+
+	// randint - returns an integer in the range [a, b]
+	"randint": {CapRandom, func(s *Stack, p params, env *RuntimeEnv) {
+		msg := "randint expects two integers"
+		expectArgs(p, msg, TypNumber, TypNumber)
+		if !isInt(p[0].Value) || !isInt(p[1].Value) {
+			panic(msg)
+		}
+		a, b := toBig(p[0].Value), toBig(p[1].Value)
+		n := new(big.Int).Sub(b, a)
+		if n.Sign() < 0 {
+			panic("randint range is empty")
+		}
+		r := new(big.Int).Rand(env.random(), n.Add(n, big.NewInt(1)))
+		s.Push(ScopeEntry{TypNumber, normInt(r.Add(r, a))})
+	}},
+
+	// choice - returns one of its arguments, or of the elements of a list
+	// given alone
+	"choice": {CapRandom, func(s *Stack, p params, env *RuntimeEnv) {
+		items := []ScopeEntry(p)
+		if len(p) == 1 && p[0].DataType == TypList {
+			items = p[0].Value.([]ScopeEntry)
+		}
+		if len(items) < 1 {
+			panic("choice expects values to choose from")
+		}
+		s.Push(items[env.random().Intn(len(items))])
+	}},
+
+	// shuffle - returns the characters of a string, or the elements of a
+	// list, in random order
+	"shuffle": {CapRandom, func(s *Stack, p params, env *RuntimeEnv) {
+		if len(p) > 0 && p[0].DataType == TypList {
+			list := append([]ScopeEntry{}, p[0].Value.([]ScopeEntry)...)
+			env.random().Shuffle(len(list), func(i, j int) {
+				list[i], list[j] = list[j], list[i]
+			})
+			s.Push(ScopeEntry{TypList, list})
+			return
+		}
+		expectArgs(p, "shuffle expects a string or list", TypString)
+		runes := []rune(p[0].Value.(string))
+		env.random().Shuffle(len(runes), func(i, j int) {
+			runes[i], runes[j] = runes[j], runes[i]
+		})
+		s.Push(ScopeEntry{TypString, string(runes)})
+	}},
+}
+
+func init() {
+	for name, b := range randBuiltins {
+		builtins[name] = b
+	}
+}
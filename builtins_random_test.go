@@ -0,0 +1,111 @@
+package main
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRandBuiltins(t *testing.T) {
+	t.Parallel()
+
+	seeded := func(seed int64) *RuntimeEnv {
+		env := testRuntimeEnv("")
+		env.rng = rand.New(rand.NewSource(seed))
+		return env
+	}
+
+	t.Run("Repeat a seeded sequence", func(t *testing.T) {
+		a, b := seeded(42), seeded(42)
+		for i := 0; i < 10; i++ {
+			assert.Equal(t, callBuiltin(a, "random").Value, callBuiltin(b, "random").Value)
+		}
+
+		callBuiltin(a, "seed", 7)
+		callBuiltin(b, "seed", 7)
+		assert.Equal(t, callBuiltin(a, "randint", 1, 1000000).Value, callBuiltin(b, "randint", 1, 1000000).Value)
+	})
+
+	t.Run("Keep each environment's generator separate", func(t *testing.T) {
+		a, b := seeded(1), seeded(1)
+		callBuiltin(a, "random")
+		assert.NotEqual(t, callBuiltin(a, "random").Value, callBuiltin(b, "random").Value)
+	})
+
+	t.Run("Seed from the clock by default", func(t *testing.T) {
+		env := testRuntimeEnv("")
+		f := callBuiltin(env, "random").Value.(float64)
+		assert.True(t, f >= 0 && f < 1)
+		assert.NotNil(t, env.rng)
+	})
+
+	t.Run("seed", func(t *testing.T) {
+		assert.PanicsWithValue(t, "seed expects an integer", func() {
+			callBuiltin(seeded(1), "seed", 1.5)
+		})
+	})
+
+	t.Run("random", func(t *testing.T) {
+		env := seeded(1)
+		for i := 0; i < 100; i++ {
+			f := callBuiltin(env, "random").Value.(float64)
+			assert.True(t, f >= 0 && f < 1)
+		}
+	})
+
+	t.Run("randint", func(t *testing.T) {
+		env := seeded(1)
+		seen := map[int64]bool{}
+		for i := 0; i < 100; i++ {
+			n := callBuiltin(env, "randint", -1, 1).Value.(int64)
+			assert.True(t, n >= -1 && n <= 1)
+			seen[n] = true
+		}
+		assert.Len(t, seen, 3)
+		assert.Equal(t, int64(5), callBuiltin(env, "randint", 5, 5).Value)
+
+		n := callBuiltin(env, "randint", 0, bigInt("18446744073709551616")).Value
+		assert.NotNil(t, n)
+		assert.PanicsWithValue(t, "randint range is empty", func() {
+			callBuiltin(env, "randint", 2, 1)
+		})
+		assert.PanicsWithValue(t, "randint expects two integers", func() {
+			callBuiltin(env, "randint", 1, 2.5)
+		})
+	})
+
+	t.Run("choice", func(t *testing.T) {
+		env := seeded(1)
+		for i := 0; i < 10; i++ {
+			assert.Contains(t, []interface{}{"a", "b", int64(3)}, callBuiltin(env, "choice", "a", "b", 3).Value)
+		}
+		assert.Equal(t, "only", callBuiltin(env, "choice", []ScopeEntry{{TypString, "only"}}).Value)
+		assert.PanicsWithValue(t, "choice expects values to choose from", func() {
+			callBuiltin(env, "choice")
+		})
+		assert.PanicsWithValue(t, "choice expects values to choose from", func() {
+			callBuiltin(env, "choice", []ScopeEntry{})
+		})
+	})
+
+	t.Run("shuffle", func(t *testing.T) {
+		env := seeded(1)
+		s := callBuiltin(env, "shuffle", "héllo").Value.(string)
+		assert.ElementsMatch(t, []rune("héllo"), []rune(s))
+		assert.Equal(t, "", callBuiltin(env, "shuffle", "").Value)
+
+		list := []ScopeEntry{{TypNumber, int64(1)}, {TypNumber, int64(2)}, {TypNumber, int64(3)}}
+		shuffled := callBuiltin(env, "shuffle", list).Value.([]ScopeEntry)
+		assert.ElementsMatch(t, list, shuffled)
+		assert.Equal(t, int64(1), list[0].Value)
+		assert.PanicsWithValue(t, "shuffle expects a string or list", func() {
+			callBuiltin(env, "shuffle", 42)
+		})
+	})
+
+	t.Run("Require the random capability", func(t *testing.T) {
+		assert.Equal(t, CapRandom, builtins["random"].caps)
+		assert.Equal(t, CapRandom, builtins["seed"].caps)
+	})
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+)
+
+// maxCachedRegexps is the most compiled patterns a regexpCache holds. A
+// program that builds many patterns of its own empties the cache rather
+// than growing it without bound.
+const maxCachedRegexps = 256
+
+// regexpCache holds the patterns a Runtime has compiled, so that a pattern
+// used in a loop is compiled only once.
+type regexpCache struct {
+	compiled map[string]*regexp.Regexp
+}
+
+func newRegexpCache() *regexpCache {
+	return &regexpCache{compiled: make(map[string]*regexp.Regexp)}
+}
+
+// regexp returns the compiled pattern, from env's cache if it has one. An
+// invalid pattern panics with an error naming the builtin name, the
+// pattern and what is wrong with it.
+func (env *RuntimeEnv) regexp(name, pattern string) *regexp.Regexp {
+	var cache *regexpCache
+	if env != nil {
+		cache = env.regexps
+	}
+	if cache != nil {
+		if re, ok := cache.compiled[pattern]; ok {
+			return re
+		}
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		panic(fmt.Sprintf("%s: invalid pattern %q: %v", name, pattern, err))
+	}
+	if cache != nil {
+		if len(cache.compiled) >= maxCachedRegexps {
+			cache.compiled = make(map[string]*regexp.Regexp)
+		}
+		cache.compiled[pattern] = re
+	}
+	return re
+}
+
+// regexpArgs returns the compiled pattern and the string of the builtin's
+// first two arguments, panicking with msg unless they are both strings.
+func regexpArgs(name string, p params, env *RuntimeEnv, msg string) (*regexp.Regexp, string) {
+	expectArgs(p, msg, TypString, TypString)
+	return env.regexp(name, p[0].Value.(string)), p[1].Value.(string)
+}
+
+// countArg returns the optional argument p[i] of the builtin, the most
+// results it may give, or -1 for all of them.
+func countArg(p params, i int, msg string) int {
+	if len(p) <= i {
+		return -1
+	}
+	n := intArgs(p, msg, i)[0]
+	if n < 0 {
+		panic(msg)
+	}
+	return n
+}
+
+// regular expression built-in functions, whose patterns have the RE2
+// syntax of Go's regexp package
+var regexpBuiltins = map[string]builtin{
+	// match - reports whether a string contains a match of a pattern
+	"match": {0, func(s *Stack, p params, env *RuntimeEnv) {
+		re, str := regexpArgs("match", p, env, "match expects a pattern and a string")
+		s.Push(ScopeEntry{TypBool, re.MatchString(str)})
+	}},
+
+	// find_all - returns a list of the matches of a pattern in a string,
+	// all of them or at most n
+	"find_all": {0, func(s *Stack, p params, env *RuntimeEnv) {
+		msg := "find_all expects a pattern, a string and an optional count"
+		re, str := regexpArgs("find_all", p, env, msg)
+		matches := re.FindAllString(str, countArg(p, 2, msg))
+		env.checkSize(len(matches))
+		s.Push(stringList(matches))
+	}},
+
+	// regex_replace - returns a string with the matches of a pattern
+	// replaced, where $1 or ${name} in the replacement stands for the text
+	// of a capture group
+	"regex_replace": {0, func(s *Stack, p params, env *RuntimeEnv) {
+		msg := "regex_replace expects a pattern, a string and a replacement"
+		expectArgs(p, msg, TypString, TypString, TypString)
+		re, str := regexpArgs("regex_replace", p, env, msg)
+		repl := p[2].Value.(string)
+		// bound the result before building it, as each match may
+		// expand to a copy of the replacement
+		if n := len(re.FindAllStringIndex(str, -1)); n > 0 && len(repl) > 0 {
+			env.checkSize(n * len(repl))
+		}
+		str = re.ReplaceAllString(str, repl)
+		env.checkSize(len(str))
+		s.Push(ScopeEntry{TypString, str})
+	}},
+
+	// regex_split - returns a list of the fields of a string split around
+	// the matches of a pattern, all of them or at most n
+	"regex_split": {0, func(s *Stack, p params, env *RuntimeEnv) {
+		msg := "regex_split expects a pattern, a string and an optional count"
+		re, str := regexpArgs("regex_split", p, env, msg)
+		fields := re.Split(str, countArg(p, 2, msg))
+		env.checkSize(len(fields))
+		s.Push(stringList(fields))
+	}},
+}
+
+func init() {
+	for name, b := range regexpBuiltins {
+		builtins[name] = b
+	}
+}
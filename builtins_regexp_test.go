@@ -0,0 +1,106 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRegexpBuiltins(t *testing.T) {
+	t.Parallel()
+
+	strs := func(strs ...string) []ScopeEntry {
+		return stringList(strs).Value.([]ScopeEntry)
+	}
+
+	t.Run("match", func(t *testing.T) {
+		assert.Equal(t, true, callBuiltin(nil, "match", `^k\w+$`, "kiwi").Value)
+		assert.Equal(t, true, callBuiltin(nil, "match", `i.i`, "kiwi").Value)
+		assert.Equal(t, false, callBuiltin(nil, "match", `^\d+$`, "12a").Value)
+		assert.Equal(t, true, callBuiltin(nil, "match", `(?i)KIWI`, "kiwi").Value)
+		assert.PanicsWithValue(t, "match expects a pattern and a string", func() {
+			callBuiltin(nil, "match", "a")
+		})
+	})
+
+	t.Run("find_all", func(t *testing.T) {
+		assert.Equal(t, strs("12", "345", "6"), callBuiltin(nil, "find_all", `\d+`, "a12b345c6").Value)
+		assert.Equal(t, strs("12", "345"), callBuiltin(nil, "find_all", `\d+`, "a12b345c6", 2).Value)
+		assert.Equal(t, strs(), callBuiltin(nil, "find_all", `\d+`, "abc").Value)
+		assert.Equal(t, strs(), callBuiltin(nil, "find_all", `\d+`, "a1", 0).Value)
+		assert.Equal(t, strs("é", "ü"), callBuiltin(nil, "find_all", `[^a-z]`, "éaü").Value)
+		assert.PanicsWithValue(t, "find_all expects a pattern, a string and an optional count", func() {
+			callBuiltin(nil, "find_all", `\d`, "1", -1)
+		})
+	})
+
+	t.Run("regex_replace", func(t *testing.T) {
+		assert.Equal(t, "b-a d-c", callBuiltin(nil, "regex_replace", `(\w)(\w)`, "ab cd", "$2-$1").Value)
+		assert.Equal(t, "<kiwi>", callBuiltin(nil, "regex_replace", `(?P<word>\w+)`, "kiwi", "<${word}>").Value)
+		assert.Equal(t, "x", callBuiltin(nil, "regex_replace", `\s+`, "  x ", "").Value)
+		assert.Equal(t, "$5", callBuiltin(nil, "regex_replace", `\d`, "5", "$$$0").Value)
+		assert.PanicsWithValue(t, "regex_replace expects a pattern, a string and a replacement", func() {
+			callBuiltin(nil, "regex_replace", `a`, "a")
+		})
+
+		env := &RuntimeEnv{maxSize: 8}
+		assert.PanicsWithValue(t, ErrSizeLimit, func() {
+			callBuiltin(env, "regex_replace", ``, "abc", "xyz")
+		})
+	})
+
+	t.Run("regex_split", func(t *testing.T) {
+		assert.Equal(t, strs("a", "b", "c"), callBuiltin(nil, "regex_split", `\s*,\s*`, "a , b,c").Value)
+		assert.Equal(t, strs("a", "b,c"), callBuiltin(nil, "regex_split", `,`, "a,b,c", 2).Value)
+		assert.Equal(t, strs(""), callBuiltin(nil, "regex_split", `,`, "").Value)
+		assert.PanicsWithValue(t, "regex_split expects a pattern, a string and an optional count", func() {
+			callBuiltin(nil, "regex_split", 1, "a")
+		})
+	})
+
+	t.Run("Test invalid patterns", func(t *testing.T) {
+		assert.PanicsWithValue(t,
+			"match: invalid pattern \"(a\": error parsing regexp: missing closing ): `(a`",
+			func() { callBuiltin(nil, "match", "(a", "a") })
+		assert.PanicsWithValue(t,
+			"regex_split: invalid pattern \"a**\": error parsing regexp: invalid nested repetition operator: `**`",
+			func() { callBuiltin(nil, "regex_split", "a**", "a") })
+	})
+
+	t.Run("Test pattern cache", func(t *testing.T) {
+		env := &RuntimeEnv{regexps: newRegexpCache()}
+		re := env.regexp("match", `\d+`)
+		assert.True(t, re == env.regexp("match", `\d+`))
+		assert.Len(t, env.regexps.compiled, 1)
+
+		for i := 0; i < maxCachedRegexps; i++ {
+			env.regexp("match", fmt.Sprintf("a{%d}", i))
+		}
+		assert.True(t, len(env.regexps.compiled) <= maxCachedRegexps)
+		assert.False(t, re == env.regexp("match", `\d+`))
+	})
+
+	t.Run("Test cache is kept by the runtime", func(t *testing.T) {
+		env := testRuntimeEnv("")
+		r := NewRuntime(env)
+		prog, err := newParser(`x := match("[0-9]", "a1")`).Parse()
+		assert.Nil(t, err)
+		assert.Nil(t, r.Run(context.Background(), prog))
+		assert.Nil(t, r.Run(context.Background(), prog))
+		assert.Len(t, r.env.regexps.compiled, 1)
+		assert.Nil(t, env.regexps)
+
+		// runtimes made from one environment may run at once
+		prog, err = newParser(`i := 0
+while i < 300 { x := match("a{${i}}", "a")  i := i + 1 }`).Parse()
+		assert.Nil(t, err)
+		done := make(chan error)
+		for i := 0; i < 2; i++ {
+			go func() { done <- NewRuntime(env).Run(context.Background(), prog) }()
+		}
+		assert.Nil(t, <-done)
+		assert.Nil(t, <-done)
+	})
+}
@@ -0,0 +1,235 @@
+package main
+
+import (
+	"math"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// strPair returns the two string arguments of the builtin name.
+func strPair(name string, p params) (string, string) {
+	expectArgs(p, name+" expects two strings", TypString, TypString)
+	return p[0].Value.(string), p[1].Value.(string)
+}
+
+// trimArgs returns the string argument of the trimming builtin name, and the
+// function that reports whether it trims a character. Whitespace is trimmed
+// unless the characters to trim are given.
+func trimArgs(name string, p params) (string, func(rune) bool) {
+	msg := name + " expects a string and optional characters to trim"
+	expectArgs(p, msg, TypString)
+	if len(p) < 2 {
+		return p[0].Value.(string), unicode.IsSpace
+	}
+	expectArgs(p, msg, TypString, TypString)
+	cutset := p[1].Value.(string)
+	return p[0].Value.(string), func(ch rune) bool {
+		return strings.ContainsRune(cutset, ch)
+	}
+}
+
+// string built-in functions, which count characters rather than bytes
+var strBuiltins = map[string]builtin{
+	// strlen - returns the number of characters in a string
+	"strlen": {0, func(s *Stack, p params, env *RuntimeEnv) {
+		expectArgs(p, "strlen expects a string", TypString)
+		s.Push(ScopeEntry{TypNumber, int64(utf8.RuneCountInString(p[0].Value.(string)))})
+	}},
+
+	// substr - returns the characters of a string from a start index, to
+	// the end or for a given length
+	"substr": {0, func(s *Stack, p params, env *RuntimeEnv) {
+		msg := "substr expects a string, a start and an optional length"
+		expectArgs(p, msg, TypString, TypNumber)
+		runes := []rune(p[0].Value.(string))
+		start := intArgs(p, msg, 1)[0]
+		length := len(runes) - start
+		if len(p) > 2 {
+			length = intArgs(p, msg, 2)[0]
+		}
+		if start < 0 || length < 0 || start+length > len(runes) {
+			panic("substr range out of bounds")
+		}
+		s.Push(ScopeEntry{TypString, string(runes[start : start+length])})
+	}},
+
+	// index - returns the index of the first occurrence of a substring, or
+	// -1 if there is none
+	"index": {0, func(s *Stack, p params, env *RuntimeEnv) {
+		str, sub := strPair("index", p)
+		i := strings.Index(str, sub)
+		if i > 0 {
+			i = utf8.RuneCountInString(str[:i])
+		}
+		s.Push(ScopeEntry{TypNumber, int64(i)})
+	}},
+
+	// contains - reports whether a string contains a substring
+	"contains": {0, func(s *Stack, p params, env *RuntimeEnv) {
+		str, sub := strPair("contains", p)
+		s.Push(ScopeEntry{TypBool, strings.Contains(str, sub)})
+	}},
+
+	// startswith - reports whether a string begins with a prefix
+	"startswith": {0, func(s *Stack, p params, env *RuntimeEnv) {
+		str, prefix := strPair("startswith", p)
+		s.Push(ScopeEntry{TypBool, strings.HasPrefix(str, prefix)})
+	}},
+
+	// endswith - reports whether a string ends with a suffix
+	"endswith": {0, func(s *Stack, p params, env *RuntimeEnv) {
+		str, suffix := strPair("endswith", p)
+		s.Push(ScopeEntry{TypBool, strings.HasSuffix(str, suffix)})
+	}},
+
+	// split - returns the list of fields of a string split around a
+	// separator, or the field at an index counting from 0; an empty
+	// separator splits between characters
+	"split": {0, func(s *Stack, p params, env *RuntimeEnv) {
+		msg := "split expects a string, a separator and an optional field index"
+		expectArgs(p, msg, TypString, TypString)
+		fields := strings.Split(p[0].Value.(string), p[1].Value.(string))
+		if len(p) < 3 {
+			env.checkSize(len(fields))
+			s.Push(stringList(fields))
+			return
+		}
+		i := intArgs(p, msg, 2)[0]
+		if i < 0 || i >= len(fields) {
+			panic("split field index out of range")
+		}
+		s.Push(ScopeEntry{TypString, fields[i]})
+	}},
+
+	// join - returns strings, given as arguments or in a list, joined by a
+	// separator
+	"join": {0, func(s *Stack, p params, env *RuntimeEnv) {
+		msg := "join expects a separator and strings"
+		expectArgs(p, msg, TypString)
+		items := p[1:]
+		if len(p) == 2 && p[1].DataType == TypList {
+			items = p[1].Value.([]ScopeEntry)
+		}
+		strs := make([]string, len(items))
+		size := 0
+		for i, e := range items {
+			if e.DataType != TypString {
+				panic(msg)
+			}
+			strs[i] = e.Value.(string)
+			size += len(strs[i]) + len(p[0].Value.(string))
+		}
+		env.checkSize(size)
+		s.Push(ScopeEntry{TypString, strings.Join(strs, p[0].Value.(string))})
+	}},
+
+	// replace - replaces occurrences of a substring, all of them or as many
+	// as a count allows
+	"replace": {0, func(s *Stack, p params, env *RuntimeEnv) {
+		msg := "replace expects three strings and an optional count"
+		expectArgs(p, msg, TypString, TypString, TypString)
+		str, old, repl := p[0].Value.(string), p[1].Value.(string), p[2].Value.(string)
+		n := -1
+		if len(p) > 3 {
+			n = intArgs(p, msg, 3)[0]
+		}
+		count := strings.Count(str, old)
+		if n >= 0 && n < count {
+			count = n
+		}
+		env.checkSize(len(str) + count*(len(repl)-len(old)))
+		s.Push(ScopeEntry{TypString, strings.Replace(str, old, repl, n)})
+	}},
+
+	// trim - removes whitespace, or the given characters, from both ends
+	// of a string
+	"trim": {0, func(s *Stack, p params, env *RuntimeEnv) {
+		str, f := trimArgs("trim", p)
+		s.Push(ScopeEntry{TypString, strings.TrimFunc(str, f)})
+	}},
+
+	// ltrim - removes whitespace, or the given characters, from the start
+	// of a string
+	"ltrim": {0, func(s *Stack, p params, env *RuntimeEnv) {
+		str, f := trimArgs("ltrim", p)
+		s.Push(ScopeEntry{TypString, strings.TrimLeftFunc(str, f)})
+	}},
+
+	// rtrim - removes whitespace, or the given characters, from the end of
+	// a string
+	"rtrim": {0, func(s *Stack, p params, env *RuntimeEnv) {
+		str, f := trimArgs("rtrim", p)
+		s.Push(ScopeEntry{TypString, strings.TrimRightFunc(str, f)})
+	}},
+
+	// upper - returns a string in upper case
+	"upper": {0, func(s *Stack, p params, env *RuntimeEnv) {
+		expectArgs(p, "upper expects a string", TypString)
+		str := strings.ToUpper(p[0].Value.(string))
+		env.checkSize(len(str))
+		s.Push(ScopeEntry{TypString, str})
+	}},
+
+	// lower - returns a string in lower case
+	"lower": {0, func(s *Stack, p params, env *RuntimeEnv) {
+		expectArgs(p, "lower expects a string", TypString)
+		str := strings.ToLower(p[0].Value.(string))
+		env.checkSize(len(str))
+		s.Push(ScopeEntry{TypString, str})
+	}},
+
+	// repeat - returns a string repeated a number of times
+	"repeat": {0, func(s *Stack, p params, env *RuntimeEnv) {
+		msg := "repeat expects a string and a count"
+		expectArgs(p, msg, TypString, TypNumber)
+		str, n := p[0].Value.(string), intArgs(p, msg, 1)[0]
+		if n < 0 {
+			panic("repeat count must not be negative")
+		}
+		if n > 0 && len(str) > math.MaxInt32/n {
+			panic(ErrSizeLimit)
+		}
+		env.checkSize(len(str) * n)
+		s.Push(ScopeEntry{TypString, strings.Repeat(str, n)})
+	}},
+
+	// reverse - returns the characters of a string in reverse order
+	"reverse": {0, func(s *Stack, p params, env *RuntimeEnv) {
+		expectArgs(p, "reverse expects a string", TypString)
+		runes := []rune(p[0].Value.(string))
+		for i, j := 0, len(runes)-1; i < j; i, j = i+1, j-1 {
+			runes[i], runes[j] = runes[j], runes[i]
+		}
+		s.Push(ScopeEntry{TypString, string(runes)})
+	}},
+
+	// ord - returns the code point of a character
+	"ord": {0, func(s *Stack, p params, env *RuntimeEnv) {
+		msg := "ord expects a single character"
+		expectArgs(p, msg, TypString)
+		str := p[0].Value.(string)
+		ch, size := utf8.DecodeRuneInString(str)
+		if str == "" || size != len(str) {
+			panic(msg)
+		}
+		s.Push(ScopeEntry{TypNumber, int64(ch)})
+	}},
+
+	// chr - returns the character with a code point
+	"chr": {0, func(s *Stack, p params, env *RuntimeEnv) {
+		msg := "chr expects a code point"
+		expectArgs(p, msg, TypNumber)
+		ch := rune(intArgs(p, msg, 0)[0])
+		if !utf8.ValidRune(ch) {
+			panic("chr: invalid code point")
+		}
+		s.Push(ScopeEntry{TypString, string(ch)})
+	}},
+}
+
+func init() {
+	for name, b := range strBuiltins {
+		builtins[name] = b
+	}
+}
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStrBuiltins(t *testing.T) {
+	t.Parallel()
+
+	env := testRuntimeEnv("")
+
+	t.Run("strlen", func(t *testing.T) {
+		assert.Equal(t, int64(5), callBuiltin(env, "strlen", "héllo").Value)
+		assert.Equal(t, int64(0), callBuiltin(env, "strlen", "").Value)
+		assert.PanicsWithValue(t, "strlen expects a string", func() {
+			callBuiltin(env, "strlen", 42)
+		})
+	})
+
+	t.Run("substr", func(t *testing.T) {
+		assert.Equal(t, "llo", callBuiltin(env, "substr", "héllo", 2).Value)
+		assert.Equal(t, "él", callBuiltin(env, "substr", "héllo", 1, 2).Value)
+		assert.Equal(t, "", callBuiltin(env, "substr", "héllo", 5).Value)
+		assert.PanicsWithValue(t, "substr range out of bounds", func() {
+			callBuiltin(env, "substr", "héllo", 3, 3)
+		})
+		assert.PanicsWithValue(t, "substr range out of bounds", func() {
+			callBuiltin(env, "substr", "héllo", -1)
+		})
+		assert.PanicsWithValue(t, "substr expects a string, a start and an optional length", func() {
+			callBuiltin(env, "substr", "héllo", 1.5)
+		})
+	})
+
+	t.Run("index", func(t *testing.T) {
+		assert.Equal(t, int64(2), callBuiltin(env, "index", "héllo", "ll").Value)
+		assert.Equal(t, int64(0), callBuiltin(env, "index", "héllo", "").Value)
+		assert.Equal(t, int64(-1), callBuiltin(env, "index", "héllo", "x").Value)
+	})
+
+	t.Run("contains", func(t *testing.T) {
+		assert.Equal(t, true, callBuiltin(env, "contains", "héllo", "él").Value)
+		assert.Equal(t, false, callBuiltin(env, "contains", "héllo", "e").Value)
+		assert.PanicsWithValue(t, "contains expects two strings", func() {
+			callBuiltin(env, "contains", "héllo")
+		})
+	})
+
+	t.Run("startswith and endswith", func(t *testing.T) {
+		assert.Equal(t, true, callBuiltin(env, "startswith", "héllo", "hé").Value)
+		assert.Equal(t, false, callBuiltin(env, "startswith", "héllo", "lo").Value)
+		assert.Equal(t, true, callBuiltin(env, "endswith", "héllo", "lo").Value)
+		assert.Equal(t, false, callBuiltin(env, "endswith", "héllo", "hé").Value)
+	})
+
+	t.Run("split", func(t *testing.T) {
+		assert.Equal(t, "b", callBuiltin(env, "split", "a,b,c", ",", 1).Value)
+		assert.Equal(t, "", callBuiltin(env, "split", "a,,c", ",", 1).Value)
+		assert.Equal(t, "é", callBuiltin(env, "split", "héllo", "", 1).Value)
+		assert.PanicsWithValue(t, "split field index out of range", func() {
+			callBuiltin(env, "split", "a,b,c", ",", 3)
+		})
+		assert.Equal(t, []ScopeEntry{{TypString, "a"}, {TypString, ""}, {TypString, "c"}},
+			callBuiltin(env, "split", "a,,c", ",").Value)
+	})
+
+	t.Run("join", func(t *testing.T) {
+		assert.Equal(t, "a, b, c", callBuiltin(env, "join", ", ", "a", "b", "c").Value)
+		assert.Equal(t, "", callBuiltin(env, "join", ", ").Value)
+		assert.Equal(t, "a-b", callBuiltin(env, "join", "-", []ScopeEntry{{TypString, "a"}, {TypString, "b"}}).Value)
+		assert.PanicsWithValue(t, "join expects a separator and strings", func() {
+			callBuiltin(env, "join", ", ", "a", 1)
+		})
+	})
+
+	t.Run("replace", func(t *testing.T) {
+		assert.Equal(t, "heLLo", callBuiltin(env, "replace", "hello", "l", "L").Value)
+		assert.Equal(t, "heLlo", callBuiltin(env, "replace", "hello", "l", "L", 1).Value)
+		assert.Equal(t, "-a-b-", callBuiltin(env, "replace", "ab", "", "-").Value)
+	})
+
+	t.Run("trim", func(t *testing.T) {
+		assert.Equal(t, "héllo", callBuiltin(env, "trim", " \théllo\n ").Value)
+		assert.Equal(t, "héllo \n", callBuiltin(env, "ltrim", " héllo \n").Value)
+		assert.Equal(t, " héllo", callBuiltin(env, "rtrim", " héllo \n").Value)
+		assert.Equal(t, "héllo", callBuiltin(env, "trim", "¡¡héllo!!", "¡!").Value)
+		assert.Equal(t, "héllo!!", callBuiltin(env, "ltrim", "¡¡héllo!!", "¡!").Value)
+		assert.Equal(t, "¡¡héllo", callBuiltin(env, "rtrim", "¡¡héllo!!", "¡!").Value)
+	})
+
+	t.Run("upper and lower", func(t *testing.T) {
+		assert.Equal(t, "HÉLLO", callBuiltin(env, "upper", "héllo").Value)
+		assert.Equal(t, "héllo", callBuiltin(env, "lower", "HÉLLO").Value)
+	})
+
+	t.Run("repeat", func(t *testing.T) {
+		assert.Equal(t, "éééé", callBuiltin(env, "repeat", "é", 4).Value)
+		assert.Equal(t, "", callBuiltin(env, "repeat", "é", 0).Value)
+		assert.PanicsWithValue(t, "repeat count must not be negative", func() {
+			callBuiltin(env, "repeat", "é", -1)
+		})
+
+		env := testRuntimeEnv("")
+		env.maxSize = 10
+		assert.PanicsWithValue(t, ErrSizeLimit, func() {
+			builtins["repeat"].fn(&Stack{}, params{{TypString, "ab"}, {TypNumber, int64(6)}}, env)
+		})
+	})
+
+	t.Run("reverse", func(t *testing.T) {
+		assert.Equal(t, "olléh", callBuiltin(env, "reverse", "héllo").Value)
+		assert.Equal(t, "", callBuiltin(env, "reverse", "").Value)
+	})
+
+	t.Run("ord and chr", func(t *testing.T) {
+		assert.Equal(t, int64(233), callBuiltin(env, "ord", "é").Value)
+		assert.Equal(t, int64(0x1F600), callBuiltin(env, "ord", "😀").Value)
+		assert.Equal(t, "é", callBuiltin(env, "chr", 233).Value)
+		assert.PanicsWithValue(t, "ord expects a single character", func() {
+			callBuiltin(env, "ord", "ab")
+		})
+		assert.PanicsWithValue(t, "ord expects a single character", func() {
+			callBuiltin(env, "ord", "")
+		})
+		assert.PanicsWithValue(t, "chr: invalid code point", func() {
+			callBuiltin(env, "chr", 0xD800)
+		})
+	})
+}
@@ -10,12 +10,53 @@ import (
 
 func testRuntimeEnv(str string) *RuntimeEnv {
 	return &RuntimeEnv{
-		strings.NewReader(str),
-		bytes.NewBuffer([]byte{}),
-		bytes.NewBuffer([]byte{}),
+		stdin:  strings.NewReader(str),
+		stdout: bytes.NewBuffer([]byte{}),
+		stderr: bytes.NewBuffer([]byte{}),
+		caps:   CapDefault,
 	}
 }
 
+// testEntry returns arg as the entry a program would pass for it. Strings,
+// ints, bools, decimals, lists and maps have their own types, an entry is
+// returned as is, and any other value, such as a float or *big.Int, is a
+// number.
+func testEntry(arg interface{}) ScopeEntry {
+	switch v := arg.(type) {
+	case ScopeEntry:
+		return v
+	case string:
+		return ScopeEntry{TypString, v}
+	case int:
+		return ScopeEntry{TypNumber, int64(v)}
+	case bool:
+		return ScopeEntry{TypBool, v}
+	case Decimal:
+		return ScopeEntry{TypDecimal, v}
+	case []ScopeEntry:
+		return ScopeEntry{TypList, v}
+	case *Map:
+		return ScopeEntry{TypMap, v}
+	}
+	return ScopeEntry{TypNumber, arg}
+}
+
+// callBuiltin calls the builtin name in env with args as testEntry makes
+// them, and returns the entry it pushes, or the zero entry if it pushes
+// none.
+func callBuiltin(env *RuntimeEnv, name string, args ...interface{}) ScopeEntry {
+	p := params{}
+	for _, arg := range args {
+		p = append(p, testEntry(arg))
+	}
+	s := &Stack{}
+	builtins[name].fn(s, p, env)
+	if s.Size() == 0 {
+		return ScopeEntry{}
+	}
+	return s.Pop().(ScopeEntry)
+}
+
 func TestBuiltins(t *testing.T) {
 	t.Parallel()
 
@@ -26,8 +67,8 @@ func TestBuiltins(t *testing.T) {
 		p := []ScopeEntry{greeting}
 		env := testRuntimeEnv("")
 
-		builtins["strlen"](s, p, env)
-		assert.Equal(t, ScopeEntry{TypNumber, 11}, s.Pop().(ScopeEntry))
+		builtins["strlen"].fn(s, p, env)
+		assert.Equal(t, ScopeEntry{TypNumber, int64(11)}, s.Pop().(ScopeEntry))
 	})
 
 	t.Run("write", func(t *testing.T) {
@@ -35,16 +76,80 @@ func TestBuiltins(t *testing.T) {
 		p := []ScopeEntry{greeting}
 		env := testRuntimeEnv(greeting.Value.(string))
 
-		builtins["write"](s, p, env)
+		builtins["write"].fn(s, p, env)
 		assert.Equal(t, greeting.Value.(string), env.stdout.(*bytes.Buffer).String())
 	})
 
+	t.Run("format", func(t *testing.T) {
+		s := &Stack{}
+		p := []ScopeEntry{{TypString, "%-6s|%5.2f"}, {TypString, "pi"}, {TypNumber, 3.14159}}
+		env := testRuntimeEnv("")
+
+		builtins["format"].fn(s, p, env)
+		assert.Equal(t, ScopeEntry{TypString, "pi    | 3.14"}, s.Pop().(ScopeEntry))
+
+		assert.PanicsWithValue(t, "format: argument 1: %d expects an integer, got \"pi\"", func() {
+			builtins["format"].fn(s, []ScopeEntry{{TypString, "%d"}, {TypString, "pi"}}, env)
+		})
+		assert.PanicsWithValue(t, "format expects a format string", func() {
+			builtins["format"].fn(s, []ScopeEntry{}, env)
+		})
+	})
+
+	t.Run("printf", func(t *testing.T) {
+		s := &Stack{}
+		p := []ScopeEntry{{TypString, "%s has %,d bytes\n"}, greeting, {TypNumber, int64(1048576)}}
+		env := testRuntimeEnv("")
+
+		builtins["printf"].fn(s, p, env)
+		assert.Equal(t, "hello world has 1,048,576 bytes\n", env.stdout.(*bytes.Buffer).String())
+	})
+
 	t.Run("read", func(t *testing.T) {
 		s := &Stack{}
 		p := []ScopeEntry{greeting}
 		env := testRuntimeEnv(greeting.Value.(string))
 
-		builtins["read"](s, p, env)
+		builtins["read"].fn(s, p, env)
 		assert.Equal(t, greeting, s.Pop().(ScopeEntry))
 	})
+
+	t.Run("assert", func(t *testing.T) {
+		s := &Stack{}
+		env := testRuntimeEnv("")
+
+		assert.NotPanics(t, func() {
+			builtins["assert"].fn(s, []ScopeEntry{{TypBool, true}}, env)
+		})
+		assert.PanicsWithValue(t, AssertionError{"assertion failed"}, func() {
+			builtins["assert"].fn(s, []ScopeEntry{{TypBool, false}}, env)
+		})
+		assert.PanicsWithValue(t, AssertionError{"oops"}, func() {
+			builtins["assert"].fn(s, []ScopeEntry{{TypBool, false}, {TypString, "oops"}}, env)
+		})
+		assert.PanicsWithValue(t, "assert expects a bool condition", func() {
+			builtins["assert"].fn(s, []ScopeEntry{greeting}, env)
+		})
+	})
+
+	t.Run("assert_eq", func(t *testing.T) {
+		s := &Stack{}
+		env := testRuntimeEnv("")
+
+		assert.NotPanics(t, func() {
+			builtins["assert_eq"].fn(s, []ScopeEntry{greeting, greeting}, env)
+		})
+		assert.PanicsWithValue(t, AssertionError{`values are not equal: "hello world" ~= 42`}, func() {
+			builtins["assert_eq"].fn(s, []ScopeEntry{greeting, {TypNumber, 42.0}}, env)
+		})
+	})
+
+	t.Run("fail", func(t *testing.T) {
+		s := &Stack{}
+		env := testRuntimeEnv("")
+
+		assert.PanicsWithValue(t, AssertionError{"failed"}, func() {
+			builtins["fail"].fn(s, []ScopeEntry{}, env)
+		})
+	})
 }
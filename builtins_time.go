@@ -0,0 +1,248 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+)
+
+// Clock tells the time builtins the current time and when a duration has
+// passed. Replacing the system clock with a fake one lets programs that
+// depend on the time run the same way each time.
+type Clock interface {
+	// Now returns the current time.
+	Now() time.Time
+	// After returns a channel that receives the time once d has passed.
+	After(d time.Duration) <-chan time.Time
+}
+
+// systemClock is the Clock of the operating system.
+type systemClock struct{}
+
+func (systemClock) Now() time.Time {
+	return time.Now()
+}
+
+func (systemClock) After(d time.Duration) <-chan time.Time {
+	return time.After(d)
+}
+
+// now returns the current time from env's clock, or from the system clock
+// if it has none.
+func (env *RuntimeEnv) now() time.Time {
+	if env.clock == nil {
+		return systemClock{}.Now()
+	}
+	return env.clock.Now()
+}
+
+// sleep waits for d to pass on env's clock, or from the system clock if it
+// has none. It panics with the context's error if the program is
+// interrupted first.
+func (env *RuntimeEnv) sleep(d time.Duration) {
+	var clock Clock = systemClock{}
+	if env.clock != nil {
+		clock = env.clock
+	}
+	if env.ctx == nil {
+		<-clock.After(d)
+		return
+	}
+	select {
+	case <-clock.After(d):
+	case <-env.ctx.Done():
+		panic(env.ctx.Err())
+	}
+}
+
+// maxMillis is the longest duration, in milliseconds, that a time.Duration
+// can hold.
+const maxMillis = int64(math.MaxInt64 / time.Millisecond)
+
+// timeDirectives maps the directives of a time layout, written after a '%',
+// to the Go layouts that format and parse them.
+var timeDirectives = map[byte]string{
+	'Y': "2006",    // year
+	'y': "06",      // year without the century
+	'm': "01",      // month, 01-12
+	'd': "02",      // day of the month, 01-31
+	'e': "_2",      // day of the month, space padded
+	'j': "002",     // day of the year, 001-366
+	'H': "15",      // hour, 00-23
+	'I': "03",      // hour, 01-12
+	'M': "04",      // minute
+	'S': "05",      // second
+	'p': "PM",      // AM or PM
+	'b': "Jan",     // abbreviated month name
+	'B': "January", // month name
+	'a': "Mon",     // abbreviated weekday name
+	'A': "Monday",  // weekday name
+	'Z': "MST",     // time zone abbreviation
+	'z': "-0700",   // time zone offset
+}
+
+// timeLayout splits layout into pieces of literal text and directives,
+// calling f with each piece and, for a directive, its Go layout.
+func timeLayout(layout string, f func(text, goLayout string)) error {
+	for i := 0; i < len(layout); i++ {
+		if layout[i] != '%' {
+			j := strings.IndexByte(layout[i:], '%')
+			if j < 0 {
+				j = len(layout) - i
+			}
+			f(layout[i:i+j], "")
+			i += j - 1
+			continue
+		}
+		if i+1 == len(layout) {
+			return errors.New("layout ends with an incomplete directive")
+		}
+		i++
+		if layout[i] == '%' {
+			f("%", "")
+			continue
+		}
+		goLayout, ok := timeDirectives[layout[i]]
+		if !ok {
+			return fmt.Errorf("unknown directive %%%c", layout[i])
+		}
+		f("", goLayout)
+	}
+	return nil
+}
+
+// formatTime formats t as layout directs.
+func formatTime(t time.Time, layout string) (string, error) {
+	var buf strings.Builder
+	err := timeLayout(layout, func(text, goLayout string) {
+		if goLayout == "" {
+			buf.WriteString(text)
+		} else {
+			buf.WriteString(t.Format(goLayout))
+		}
+	})
+	return buf.String(), err
+}
+
+// parseTime parses s as a time written as layout directs, in loc unless s
+// gives its time zone.
+func parseTime(s, layout string, loc *time.Location) (time.Time, error) {
+	var buf strings.Builder
+	err := timeLayout(layout, func(text, goLayout string) {
+		buf.WriteString(text + goLayout)
+	})
+	if err != nil {
+		return time.Time{}, err
+	}
+	t, err := time.ParseInLocation(buf.String(), s, loc)
+	if err != nil {
+		return t, fmt.Errorf("cannot parse %q as %q", s, layout)
+	}
+	return t, nil
+}
+
+// zoneArg returns the time zone named by the argument p[i] of the builtin
+// name, or UTC if there is none.
+func zoneArg(name string, p params, i int, msg string) *time.Location {
+	if len(p) <= i {
+		return time.UTC
+	}
+	if p[i].DataType != TypString {
+		panic(msg)
+	}
+	loc, err := time.LoadLocation(p[i].Value.(string))
+	if err != nil {
+		panic(name + ": unknown time zone " + p[i].Value.(string))
+	}
+	return loc
+}
+
+// time built-in functions, which give times as milliseconds since the Unix
+// epoch and durations as milliseconds
+var timeBuiltins = map[string]builtin{
+	// now - returns the current time
+	"now": {CapClock, func(s *Stack, p params, env *RuntimeEnv) {
+		s.Push(ScopeEntry{TypNumber, env.now().UnixMilli()})
+	}},
+
+	// unix - returns the current time in whole seconds since the Unix
+	// epoch
+	"unix": {CapClock, func(s *Stack, p params, env *RuntimeEnv) {
+		s.Push(ScopeEntry{TypNumber, env.now().Unix()})
+	}},
+
+	// sleep - waits for a number of milliseconds
+	"sleep": {CapClock, func(s *Stack, p params, env *RuntimeEnv) {
+		msg := "sleep expects a number of milliseconds"
+		expectArgs(p, msg, TypNumber)
+		ms, ok := p[0].Value.(int64)
+		if !ok || ms > maxMillis {
+			panic(msg)
+		}
+		if ms < 0 {
+			panic("sleep duration must not be negative")
+		}
+		env.sleep(time.Duration(ms) * time.Millisecond)
+	}},
+
+	// format_time - formats a time as a layout directs, in UTC or a given
+	// time zone
+	"format_time": {0, func(s *Stack, p params, env *RuntimeEnv) {
+		msg := "format_time expects a time, a layout and an optional time zone"
+		expectArgs(p, msg, TypNumber, TypString)
+		ms, ok := p[0].Value.(int64)
+		if !ok {
+			panic(msg)
+		}
+		loc := zoneArg("format_time", p, 2, msg)
+		str, err := formatTime(time.UnixMilli(ms).In(loc), p[1].Value.(string))
+		if err != nil {
+			panic("format_time: " + err.Error())
+		}
+		env.checkSize(len(str))
+		s.Push(ScopeEntry{TypString, str})
+	}},
+
+	// parse_time - returns the time a string gives as a layout directs, in
+	// UTC or a given time zone unless the string gives its own
+	"parse_time": {0, func(s *Stack, p params, env *RuntimeEnv) {
+		msg := "parse_time expects a string, a layout and an optional time zone"
+		expectArgs(p, msg, TypString, TypString)
+		loc := zoneArg("parse_time", p, 2, msg)
+		t, err := parseTime(p[0].Value.(string), p[1].Value.(string), loc)
+		if err != nil {
+			panic("parse_time: " + err.Error())
+		}
+		s.Push(ScopeEntry{TypNumber, t.UnixMilli()})
+	}},
+
+	// duration - returns the milliseconds in a duration such as "1h30m"
+	"duration": {0, func(s *Stack, p params, env *RuntimeEnv) {
+		expectArgs(p, "duration expects a string", TypString)
+		d, err := time.ParseDuration(p[0].Value.(string))
+		if err != nil {
+			panic(fmt.Sprintf("duration: cannot parse %q", p[0].Value.(string)))
+		}
+		s.Push(ScopeEntry{TypNumber, d.Milliseconds()})
+	}},
+
+	// format_duration - formats a number of milliseconds as a duration
+	// such as "1h30m0s"
+	"format_duration": {0, func(s *Stack, p params, env *RuntimeEnv) {
+		msg := "format_duration expects a number of milliseconds"
+		expectArgs(p, msg, TypNumber)
+		ms, ok := p[0].Value.(int64)
+		if !ok || ms > maxMillis || ms < -maxMillis {
+			panic(msg)
+		}
+		s.Push(ScopeEntry{TypString, (time.Duration(ms) * time.Millisecond).String()})
+	}},
+}
+
+func init() {
+	for name, b := range timeBuiltins {
+		builtins[name] = b
+	}
+}
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeClock is a Clock whose time only moves when a program sleeps.
+type fakeClock struct {
+	t time.Time
+}
+
+func (c *fakeClock) Now() time.Time {
+	return c.t
+}
+
+func (c *fakeClock) After(d time.Duration) <-chan time.Time {
+	c.t = c.t.Add(d)
+	ch := make(chan time.Time, 1)
+	ch <- c.t
+	return ch
+}
+
+func TestTimeBuiltins(t *testing.T) {
+	t.Parallel()
+
+	// 2024-02-29 13:04:05.250 UTC, a Thursday
+	const start = int64(1709211845250)
+
+	faked := func() *RuntimeEnv {
+		env := testRuntimeEnv("")
+		env.clock = &fakeClock{time.UnixMilli(start)}
+		return env
+	}
+
+	t.Run("now and unix", func(t *testing.T) {
+		env := faked()
+		assert.Equal(t, start, callBuiltin(env, "now").Value)
+		assert.Equal(t, start/1000, callBuiltin(env, "unix").Value)
+
+		before := time.Now().UnixMilli()
+		now := callBuiltin(testRuntimeEnv(""), "now").Value.(int64)
+		assert.True(t, now >= before)
+	})
+
+	t.Run("sleep", func(t *testing.T) {
+		env := faked()
+		callBuiltin(env, "sleep", 1500)
+		assert.Equal(t, start+1500, callBuiltin(env, "now").Value)
+		assert.PanicsWithValue(t, "sleep duration must not be negative", func() {
+			callBuiltin(env, "sleep", -1)
+		})
+		assert.PanicsWithValue(t, "sleep expects a number of milliseconds", func() {
+			callBuiltin(env, "sleep", 1.5)
+		})
+	})
+
+	t.Run("Interrupt sleep", func(t *testing.T) {
+		env := testRuntimeEnv("")
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		env.ctx = ctx
+		assert.PanicsWithValue(t, context.Canceled, func() {
+			callBuiltin(env, "sleep", 60000)
+		})
+	})
+
+	t.Run("format_time", func(t *testing.T) {
+		env := faked()
+		assert.Equal(t, "2024-02-29 13:04:05",
+			callBuiltin(env, "format_time", start, "%Y-%m-%d %H:%M:%S").Value)
+		assert.Equal(t, "Thu Feb 29 01:04 PM UTC, day 060, 100%",
+			callBuiltin(env, "format_time", start, "%a %b %e %I:%M %p %Z, day %j, 100%%").Value)
+		assert.Equal(t, "Thursday 29 February 2024 08:04 -0500",
+			callBuiltin(env, "format_time", start, "%A %d %B %Y %H:%M %z", "America/New_York").Value)
+		assert.PanicsWithValue(t, "format_time: unknown directive %q", func() {
+			callBuiltin(env, "format_time", start, "%q")
+		})
+		assert.PanicsWithValue(t, "format_time: layout ends with an incomplete directive", func() {
+			callBuiltin(env, "format_time", start, "%Y%")
+		})
+		assert.PanicsWithValue(t, "format_time: unknown time zone Mars/Olympus", func() {
+			callBuiltin(env, "format_time", start, "%Y", "Mars/Olympus")
+		})
+		assert.PanicsWithValue(t, "format_time expects a time, a layout and an optional time zone", func() {
+			callBuiltin(env, "format_time", "now", "%Y")
+		})
+	})
+
+	t.Run("parse_time", func(t *testing.T) {
+		env := faked()
+		assert.Equal(t, start-250,
+			callBuiltin(env, "parse_time", "2024-02-29 13:04:05", "%Y-%m-%d %H:%M:%S").Value)
+		assert.Equal(t, start-250,
+			callBuiltin(env, "parse_time", "29/02/24 08:04:05 -0500", "%d/%m/%y %H:%M:%S %z").Value)
+		assert.Equal(t, start-250,
+			callBuiltin(env, "parse_time", "2024-02-29 08:04:05", "%Y-%m-%d %H:%M:%S", "America/New_York").Value)
+		assert.PanicsWithValue(t, `parse_time: cannot parse "2024-13-01" as "%Y-%m-%d"`, func() {
+			callBuiltin(env, "parse_time", "2024-13-01", "%Y-%m-%d")
+		})
+	})
+
+	t.Run("Durations", func(t *testing.T) {
+		env := faked()
+		assert.Equal(t, int64(5400000), callBuiltin(env, "duration", "1h30m").Value)
+		assert.Equal(t, int64(250), callBuiltin(env, "duration", "250ms").Value)
+		assert.Equal(t, "1h30m0s", callBuiltin(env, "format_duration", 5400000).Value)
+		assert.Equal(t, "-1.5s", callBuiltin(env, "format_duration", -1500).Value)
+		assert.PanicsWithValue(t, `duration: cannot parse "soon"`, func() {
+			callBuiltin(env, "duration", "soon")
+		})
+	})
+
+	t.Run("Require the clock capability", func(t *testing.T) {
+		assert.Equal(t, CapClock, builtins["now"].caps)
+		assert.Equal(t, CapClock, builtins["sleep"].caps)
+		assert.Equal(t, Capability(0), builtins["format_time"].caps)
+	})
+}
@@ -0,0 +1,106 @@
+package main
+
+// Lists are held as []ScopeEntry values and maps as *Map values. Neither is
+// changed once a program can see it: the builtins that add to or remove
+// from a container return a new one, so containers can be shared freely.
+
+// Map maps strings to values, remembering the order in which its keys were
+// first added.
+type Map struct {
+	keys []string
+	vals map[string]ScopeEntry
+}
+
+// NewMap returns an empty map.
+func NewMap() *Map {
+	return &Map{vals: make(map[string]ScopeEntry)}
+}
+
+// Len returns the number of keys in m.
+func (m *Map) Len() int {
+	return len(m.keys)
+}
+
+// Keys returns the keys of m in the order they were added.
+func (m *Map) Keys() []string {
+	return m.keys
+}
+
+// Get returns the value of key, reporting whether m has it.
+func (m *Map) Get(key string) (ScopeEntry, bool) {
+	e, ok := m.vals[key]
+	return e, ok
+}
+
+// Set sets the value of key, which keeps its place among the keys if m
+// already has it.
+func (m *Map) Set(key string, e ScopeEntry) {
+	if _, ok := m.vals[key]; !ok {
+		m.keys = append(m.keys, key)
+	}
+	m.vals[key] = e
+}
+
+// Delete removes key from m.
+func (m *Map) Delete(key string) {
+	if _, ok := m.vals[key]; !ok {
+		return
+	}
+	delete(m.vals, key)
+	for i, k := range m.keys {
+		if k == key {
+			m.keys = append(m.keys[:i:i], m.keys[i+1:]...)
+			break
+		}
+	}
+}
+
+// Copy returns a copy of m that can be changed without changing m.
+func (m *Map) Copy() *Map {
+	c := &Map{
+		keys: append([]string(nil), m.keys...),
+		vals: make(map[string]ScopeEntry, len(m.vals)),
+	}
+	for k, v := range m.vals {
+		c.vals[k] = v
+	}
+	return c
+}
+
+// stringList returns strs as a list of strings.
+func stringList(strs []string) ScopeEntry {
+	list := make([]ScopeEntry, len(strs))
+	for i, str := range strs {
+		list[i] = ScopeEntry{TypString, str}
+	}
+	return ScopeEntry{TypList, list}
+}
+
+// listsEqual reports whether the lists a and b hold equal values in the
+// same order.
+func listsEqual(a, b []ScopeEntry) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if !valuesEqual(a[i], b[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// mapsEqual reports whether the maps a and b have the same keys with equal
+// values, in any order.
+func mapsEqual(a, b *Map) bool {
+	if a.Len() != b.Len() {
+		return false
+	}
+	for _, k := range a.keys {
+		v, ok := b.Get(k)
+		if !ok || !valuesEqual(a.vals[k], v) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestContainers(t *testing.T) {
+	t.Parallel()
+
+	num := func(n int64) ScopeEntry { return ScopeEntry{TypNumber, n} }
+	str := func(s string) ScopeEntry { return ScopeEntry{TypString, s} }
+
+	t.Run("Keep map keys in the order added", func(t *testing.T) {
+		m := NewMap()
+		m.Set("b", num(1))
+		m.Set("a", num(2))
+		m.Set("b", num(3))
+		assert.Equal(t, []string{"b", "a"}, m.Keys())
+		assert.Equal(t, 2, m.Len())
+		v, ok := m.Get("b")
+		assert.True(t, ok)
+		assert.Equal(t, num(3), v)
+		_, ok = m.Get("c")
+		assert.False(t, ok)
+	})
+
+	t.Run("Delete map keys", func(t *testing.T) {
+		m := NewMap()
+		m.Set("a", num(1))
+		m.Set("b", num(2))
+		m.Set("c", num(3))
+		m.Delete("b")
+		m.Delete("x")
+		assert.Equal(t, []string{"a", "c"}, m.Keys())
+		_, ok := m.Get("b")
+		assert.False(t, ok)
+	})
+
+	t.Run("Copy maps", func(t *testing.T) {
+		m := NewMap()
+		m.Set("a", num(1))
+		c := m.Copy()
+		c.Set("b", num(2))
+		c.Delete("a")
+		assert.Equal(t, []string{"a"}, m.Keys())
+		assert.Equal(t, []string{"b"}, c.Keys())
+	})
+
+	t.Run("Compare containers by content", func(t *testing.T) {
+		a := ScopeEntry{TypList, []ScopeEntry{num(1), str("x")}}
+		b := ScopeEntry{TypList, []ScopeEntry{{TypNumber, 1.0}, str("x")}}
+		assert.True(t, valuesEqual(a, b))
+		assert.False(t, valuesEqual(a, ScopeEntry{TypList, []ScopeEntry{num(1)}}))
+		nan := ScopeEntry{TypList, []ScopeEntry{{TypNumber, math.NaN()}}}
+		assert.False(t, valuesEqual(nan, nan))
+
+		m1, m2 := NewMap(), NewMap()
+		m1.Set("a", num(1))
+		m1.Set("b", a)
+		m2.Set("b", b)
+		m2.Set("a", num(1))
+		assert.True(t, valuesEqual(ScopeEntry{TypMap, m1}, ScopeEntry{TypMap, m2}))
+		m2.Set("c", num(1))
+		assert.False(t, valuesEqual(ScopeEntry{TypMap, m1}, ScopeEntry{TypMap, m2}))
+
+		assert.True(t, valuesEqual(ScopeEntry{TypNull, nil}, ScopeEntry{TypNull, nil}))
+		assert.False(t, valuesEqual(ScopeEntry{TypNull, nil}, num(0)))
+	})
+}
@@ -0,0 +1,265 @@
+package main
+
+import (
+	"fmt"
+	"html"
+	"io"
+	"sort"
+)
+
+type (
+	// covFunc records how often a function defined in the program was
+	// called.
+	covFunc struct {
+		name  string
+		line  int
+		calls int64
+	}
+
+	// covBranch records how often each way of a conditional statement was
+	// taken.
+	covBranch struct {
+		pos   Pos
+		taken [2]int64
+	}
+)
+
+// Coverage implements the BranchTracer interface to record which statements,
+// if/else branches and while loop bodies of a program were executed.
+type Coverage struct {
+	file     string
+	stmts    map[Pos]int64
+	branches map[Pos]*covBranch
+	funcs    map[string]*covFunc
+}
+
+// NewCoverage returns a coverage recorder for prog, which was read from
+// file. Every statement of prog is registered so statements that never run
+// are reported too.
+func NewCoverage(file string, prog *AstProgramNode) *Coverage {
+	c := &Coverage{
+		file:     file,
+		stmts:    make(map[Pos]int64),
+		branches: make(map[Pos]*covBranch),
+		funcs:    make(map[string]*covFunc),
+	}
+	c.register(prog.Stmts)
+	return c
+}
+
+// register records the statements in list and in the blocks they contain.
+func (c *Coverage) register(list []AstNode) {
+	for _, n := range list {
+		if s, ok := n.(AstStmtNode); ok {
+			c.stmts[s.Position()] = 0
+		}
+		switch n := n.(type) {
+		case *AstFuncDefNode:
+			c.funcs[n.Name] = &covFunc{name: n.Name, line: n.Line}
+			c.register(n.Body)
+		case *AstIfNode:
+			c.branches[n.Pos] = &covBranch{pos: n.Pos}
+			c.register(n.Body)
+			c.register(n.Else)
+		case *AstWhileNode:
+			c.branches[n.Pos] = &covBranch{pos: n.Pos}
+			c.register(n.Body)
+		}
+	}
+}
+
+// Stmt counts the statement about to execute.
+func (c *Coverage) Stmt(r *Runtime, n AstStmtNode) {
+	c.stmts[n.Position()]++
+}
+
+// Enter counts calls to the program's functions.
+func (c *Coverage) Enter(r *Runtime, f *Frame) {
+	if fn, ok := c.funcs[f.Name]; ok {
+		fn.calls++
+	}
+}
+
+// Leave is part of the Tracer interface.
+func (c *Coverage) Leave(r *Runtime, f *Frame) {}
+
+// Branch counts the branch taken by the conditional statement n.
+func (c *Coverage) Branch(r *Runtime, n AstStmtNode, taken int) {
+	if b, ok := c.branches[n.Position()]; ok {
+		b.taken[taken]++
+	}
+}
+
+// lines returns the execution count of each line holding a statement. A
+// line with several statements reports the most executed one.
+func (c *Coverage) lines() map[int]int64 {
+	lines := make(map[int]int64)
+	for pos, count := range c.stmts {
+		if prev, ok := lines[pos.Line]; !ok || count > prev {
+			lines[pos.Line] = count
+		}
+	}
+	return lines
+}
+
+// sortedBranches returns the branch points in source order.
+func (c *Coverage) sortedBranches() []*covBranch {
+	var list []*covBranch
+	for _, b := range c.branches {
+		list = append(list, b)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].pos.Line != list[j].pos.Line {
+			return list[i].pos.Line < list[j].pos.Line
+		}
+		return list[i].pos.Col < list[j].pos.Col
+	})
+	return list
+}
+
+// Percent returns the percentage of statements and of branches executed.
+func (c *Coverage) Percent() (stmts, branches float64) {
+	stmts, branches = 100, 100
+	hit := 0
+	for _, count := range c.stmts {
+		if count > 0 {
+			hit++
+		}
+	}
+	if len(c.stmts) > 0 {
+		stmts = 100 * float64(hit) / float64(len(c.stmts))
+	}
+	hit = 0
+	for _, b := range c.branches {
+		for _, count := range b.taken {
+			if count > 0 {
+				hit++
+			}
+		}
+	}
+	if len(c.branches) > 0 {
+		branches = 100 * float64(hit) / float64(2*len(c.branches))
+	}
+	return stmts, branches
+}
+
+// WriteSummary writes a one-line summary of the coverage.
+func (c *Coverage) WriteSummary(w io.Writer) {
+	stmts, branches := c.Percent()
+	fmt.Fprintf(w, "coverage: %.1f%% of statements, %.1f%% of branches\n",
+		stmts, branches)
+}
+
+// WriteLcov writes the coverage as an lcov tracefile.
+func (c *Coverage) WriteLcov(w io.Writer) {
+	fmt.Fprintln(w, "TN:")
+	fmt.Fprintf(w, "SF:%s\n", c.file)
+
+	var funcs []*covFunc
+	for _, f := range c.funcs {
+		funcs = append(funcs, f)
+	}
+	sort.Slice(funcs, func(i, j int) bool { return funcs[i].line < funcs[j].line })
+	hit := 0
+	for _, f := range funcs {
+		fmt.Fprintf(w, "FN:%d,%s\n", f.line, f.name)
+	}
+	for _, f := range funcs {
+		fmt.Fprintf(w, "FNDA:%d,%s\n", f.calls, f.name)
+		if f.calls > 0 {
+			hit++
+		}
+	}
+	fmt.Fprintf(w, "FNF:%d\nFNH:%d\n", len(funcs), hit)
+
+	blocks := make(map[int]int)
+	hit = 0
+	for _, b := range c.sortedBranches() {
+		block := blocks[b.pos.Line]
+		blocks[b.pos.Line]++
+		reached := b.taken[0]+b.taken[1] > 0
+		for i, count := range b.taken {
+			taken := "-"
+			if reached {
+				taken = fmt.Sprint(count)
+			}
+			if count > 0 {
+				hit++
+			}
+			fmt.Fprintf(w, "BRDA:%d,%d,%d,%s\n", b.pos.Line, block, i, taken)
+		}
+	}
+	fmt.Fprintf(w, "BRF:%d\nBRH:%d\n", 2*len(c.branches), hit)
+
+	lines := c.lines()
+	var nums []int
+	for line := range lines {
+		nums = append(nums, line)
+	}
+	sort.Ints(nums)
+	hit = 0
+	for _, line := range nums {
+		fmt.Fprintf(w, "DA:%d,%d\n", line, lines[line])
+		if lines[line] > 0 {
+			hit++
+		}
+	}
+	fmt.Fprintf(w, "LF:%d\nLH:%d\n", len(nums), hit)
+	fmt.Fprintln(w, "end_of_record")
+}
+
+const coverageHTMLHeader = `<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>%s coverage</title>
+<style>
+body { font-family: sans-serif; }
+pre { font-family: monospace; margin: 0; }
+.hit { background: #dfd; }
+.miss { background: #fdd; }
+.partial { background: #ffd; }
+.count { color: #888; display: inline-block; text-align: right; width: 6em; }
+</style>
+</head>
+<body>
+<h1>%s</h1>
+<p>%.1f%% of statements, %.1f%% of branches</p>
+`
+
+// WriteHTML writes the coverage as an HTML page showing the source lines of
+// the program, shaded by whether they were executed. Lines whose branches
+// were not all taken are marked as partially covered.
+func (c *Coverage) WriteHTML(w io.Writer, source []string) {
+	stmts, branches := c.Percent()
+	name := html.EscapeString(c.file)
+	fmt.Fprintf(w, coverageHTMLHeader, name, name, stmts, branches)
+
+	lines := c.lines()
+	partial := make(map[int]bool)
+	for _, b := range c.branches {
+		if b.taken[0] == 0 || b.taken[1] == 0 {
+			partial[b.pos.Line] = true
+		}
+	}
+	for i, text := range source {
+		line := i + 1
+		count, ok := lines[line]
+		class, label := "", ""
+		switch {
+		case !ok:
+		case count == 0:
+			class = "miss"
+			label = "0"
+		case partial[line]:
+			class = "partial"
+			label = fmt.Sprint(count)
+		default:
+			class = "hit"
+			label = fmt.Sprint(count)
+		}
+		fmt.Fprintf(w, "<pre class=\"%s\"><span class=\"count\">%s</span> %4d  %s</pre>\n",
+			class, label, line, html.EscapeString(text))
+	}
+	fmt.Fprintln(w, "</body>\n</html>")
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const coverSource = `func sign n {
+  if n < 0 {
+    return "-"
+  } else {
+    return "+"
+  }
+}
+i := 0
+while i < 2 {
+  i := i + 1
+  s := sign(i)
+}
+`
+
+func runCoverage(src string) *Coverage {
+	prog, _ := newParser(src).Parse()
+	c := NewCoverage("test.kw", prog)
+	r := NewRuntime(testRuntimeEnv(""))
+	r.AddTracer(c)
+	prog.Accept(r)
+	return c
+}
+
+func TestCoverage(t *testing.T) {
+	t.Parallel()
+
+	t.Run("Record statements", func(t *testing.T) {
+		c := runCoverage(coverSource)
+		assert.Equal(t, int64(1), c.stmts[Pos{8, 1}])
+		assert.Equal(t, int64(2), c.stmts[Pos{10, 3}])
+		assert.Equal(t, int64(0), c.stmts[Pos{3, 5}])
+		assert.Equal(t, int64(2), c.stmts[Pos{5, 5}])
+	})
+
+	t.Run("Record branches", func(t *testing.T) {
+		c := runCoverage(coverSource)
+		assert.Equal(t, [2]int64{0, 2}, c.branches[Pos{2, 3}].taken)
+		assert.Equal(t, [2]int64{2, 1}, c.branches[Pos{9, 1}].taken)
+	})
+
+	t.Run("Compute percentages", func(t *testing.T) {
+		c := runCoverage(coverSource)
+		stmts, branches := c.Percent()
+		assert.InDelta(t, 100*7.0/8.0, stmts, 0.001)
+		assert.InDelta(t, 75.0, branches, 0.001)
+	})
+
+	t.Run("Write lcov", func(t *testing.T) {
+		c := runCoverage(coverSource)
+		buf := &bytes.Buffer{}
+		c.WriteLcov(buf)
+		out := buf.String()
+		assert.True(t, strings.HasPrefix(out, "TN:\nSF:test.kw\n"))
+		assert.Contains(t, out, "FN:1,sign\nFNDA:2,sign\nFNF:1\nFNH:1\n")
+		assert.Contains(t, out, "BRDA:2,0,0,0\nBRDA:2,0,1,2\n")
+		assert.Contains(t, out, "DA:3,0\n")
+		assert.Contains(t, out, "DA:10,2\n")
+		assert.True(t, strings.HasSuffix(out, "end_of_record\n"))
+	})
+
+	t.Run("Write lcov for unreached branch", func(t *testing.T) {
+		c := runCoverage("if false {\n  if true {\n  }\n}\n")
+		buf := &bytes.Buffer{}
+		c.WriteLcov(buf)
+		assert.Contains(t, buf.String(), "BRDA:2,0,0,-\nBRDA:2,0,1,-\n")
+	})
+
+	t.Run("Write HTML", func(t *testing.T) {
+		c := runCoverage(coverSource)
+		buf := &bytes.Buffer{}
+		c.WriteHTML(buf, strings.Split(coverSource, "\n"))
+		out := buf.String()
+		assert.Contains(t, out, `<pre class="miss"><span class="count">0</span>    3      return &#34;-&#34;</pre>`)
+		assert.Contains(t, out, `<pre class="partial"><span class="count">2</span>    2    if n &lt; 0 {</pre>`)
+		assert.Contains(t, out, `<pre class="hit"><span class="count">2</span>   10    i := i + 1</pre>`)
+	})
+}
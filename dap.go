@@ -0,0 +1,348 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// dapRequest is a request sent by a Debug Adapter Protocol client.
+type dapRequest struct {
+	Seq       int             `json:"seq"`
+	Command   string          `json:"command"`
+	Arguments json.RawMessage `json:"arguments"`
+}
+
+// dapFrame is a copy of a runtime frame taken while the program is paused,
+// so the client can inspect it without racing the runtime.
+type dapFrame struct {
+	name string
+	line int
+	col  int
+	vars []map[string]interface{}
+}
+
+// DapServer is a DebugFrontend that speaks the Debug Adapter Protocol, as
+// editors expect of a debug adapter launched with its stdin and stdout
+// attached to the editor.
+type DapServer struct {
+	in  *bufio.Reader
+	out io.Writer
+
+	mu     sync.Mutex // guards out, seq, frames and paused
+	seq    int
+	frames []dapFrame
+	paused bool
+
+	debugger *Debugger
+	resume   chan DebugAction
+	program  string
+	prog     *AstProgramNode
+}
+
+// NewDapServer returns a server reading requests from in and writing
+// responses and events to out.
+func NewDapServer(in io.Reader, out io.Writer) *DapServer {
+	s := &DapServer{
+		in:     bufio.NewReader(in),
+		out:    out,
+		resume: make(chan DebugAction),
+	}
+	s.debugger = NewDebugger(s, false)
+	return s
+}
+
+// Serve handles requests until the client disconnects or closes the input
+// stream.
+func (s *DapServer) Serve() error {
+	for {
+		req, err := s.read()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if !s.handle(req) {
+			return nil
+		}
+	}
+}
+
+// read decodes the next request, framed by a Content-Length header.
+func (s *DapServer) read() (*dapRequest, error) {
+	header, err := textproto.NewReader(s.in).ReadMIMEHeader()
+	if err != nil {
+		return nil, err
+	}
+	length, err := strconv.Atoi(header.Get("Content-Length"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid Content-Length header: %v", err)
+	}
+	body := make([]byte, length)
+	if _, err := io.ReadFull(s.in, body); err != nil {
+		return nil, err
+	}
+	req := &dapRequest{}
+	if err := json.Unmarshal(body, req); err != nil {
+		return nil, err
+	}
+	return req, nil
+}
+
+// send encodes and writes a message, filling in its sequence number.
+func (s *DapServer) send(msg map[string]interface{}) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.seq++
+	msg["seq"] = s.seq
+	body, _ := json.Marshal(msg)
+	fmt.Fprintf(s.out, "Content-Length: %d\r\n\r\n%s", len(body), body)
+}
+
+// respond sends a response to req. A non-empty message marks the request
+// as failed.
+func (s *DapServer) respond(req *dapRequest, body interface{}, message string) {
+	msg := map[string]interface{}{
+		"type":        "response",
+		"request_seq": req.Seq,
+		"command":     req.Command,
+		"success":     message == "",
+	}
+	if message != "" {
+		msg["message"] = message
+	}
+	if body != nil {
+		msg["body"] = body
+	}
+	s.send(msg)
+}
+
+// event sends an event with the given body.
+func (s *DapServer) event(name string, body interface{}) {
+	msg := map[string]interface{}{
+		"type":  "event",
+		"event": name,
+	}
+	if body != nil {
+		msg["body"] = body
+	}
+	s.send(msg)
+}
+
+// handle processes a single request and reports whether the session
+// continues.
+func (s *DapServer) handle(req *dapRequest) bool {
+	switch req.Command {
+	case "initialize":
+		s.respond(req, map[string]interface{}{
+			"supportsConfigurationDoneRequest": true,
+		}, "")
+		s.event("initialized", nil)
+
+	case "launch":
+		var args struct {
+			Program     string `json:"program"`
+			StopOnEntry bool   `json:"stopOnEntry"`
+		}
+		json.Unmarshal(req.Arguments, &args)
+		prog, err := parseFile(args.Program)
+		if err != nil {
+			s.respond(req, nil, err.Error())
+			return true
+		}
+		s.program, s.prog = args.Program, prog
+		if args.StopOnEntry {
+			s.debugger.Pause()
+		}
+		s.respond(req, nil, "")
+
+	case "setBreakpoints":
+		var args struct {
+			Breakpoints []struct {
+				Line int `json:"line"`
+			} `json:"breakpoints"`
+		}
+		json.Unmarshal(req.Arguments, &args)
+		s.debugger.ClearBreakpoints()
+		result := []map[string]interface{}{}
+		for _, bp := range args.Breakpoints {
+			s.debugger.SetBreakpoint(bp.Line)
+			result = append(result, map[string]interface{}{
+				"verified": true,
+				"line":     bp.Line,
+			})
+		}
+		s.respond(req, map[string]interface{}{"breakpoints": result}, "")
+
+	case "configurationDone":
+		if s.prog == nil {
+			s.respond(req, nil, "no program has been launched")
+			return true
+		}
+		s.respond(req, nil, "")
+		go s.run()
+
+	case "threads":
+		s.respond(req, map[string]interface{}{
+			"threads": []map[string]interface{}{{"id": 1, "name": "main"}},
+		}, "")
+
+	case "stackTrace":
+		s.mu.Lock()
+		frames := []map[string]interface{}{}
+		for i := len(s.frames) - 1; i >= 0; i-- {
+			frames = append(frames, map[string]interface{}{
+				"id":     i,
+				"name":   s.frames[i].name,
+				"line":   s.frames[i].line,
+				"column": s.frames[i].col,
+				"source": map[string]interface{}{"path": s.program},
+			})
+		}
+		s.mu.Unlock()
+		s.respond(req, map[string]interface{}{
+			"stackFrames": frames,
+			"totalFrames": len(frames),
+		}, "")
+
+	case "scopes":
+		var args struct {
+			FrameID int `json:"frameId"`
+		}
+		json.Unmarshal(req.Arguments, &args)
+		s.respond(req, map[string]interface{}{
+			"scopes": []map[string]interface{}{{
+				"name":               "Locals",
+				"variablesReference": args.FrameID + 1,
+				"expensive":          false,
+			}},
+		}, "")
+
+	case "variables":
+		var args struct {
+			VariablesReference int `json:"variablesReference"`
+		}
+		json.Unmarshal(req.Arguments, &args)
+		vars := []map[string]interface{}{}
+		s.mu.Lock()
+		if i := args.VariablesReference - 1; i >= 0 && i < len(s.frames) {
+			vars = s.frames[i].vars
+		}
+		s.mu.Unlock()
+		s.respond(req, map[string]interface{}{"variables": vars}, "")
+
+	case "continue":
+		s.respond(req, map[string]interface{}{"allThreadsContinued": true}, "")
+		s.proceed(DebugContinue)
+	case "next":
+		s.respond(req, nil, "")
+		s.proceed(DebugStepOver)
+	case "stepIn":
+		s.respond(req, nil, "")
+		s.proceed(DebugStepInto)
+	case "stepOut":
+		s.respond(req, nil, "")
+		s.proceed(DebugStepOut)
+	case "pause":
+		s.debugger.Pause()
+		s.respond(req, nil, "")
+
+	case "disconnect", "terminate":
+		s.debugger.Stop()
+		s.proceed(DebugQuit)
+		s.respond(req, nil, "")
+		return false
+
+	default:
+		s.respond(req, nil, "unsupported request "+req.Command)
+	}
+	return true
+}
+
+// proceed resumes the paused program with action. It does nothing while the
+// program is running.
+func (s *DapServer) proceed(action DebugAction) {
+	s.mu.Lock()
+	paused := s.paused
+	s.paused = false
+	s.mu.Unlock()
+	if paused {
+		s.resume <- action
+	}
+}
+
+// run executes the launched program, forwarding its output to the client
+// and reporting when it ends.
+func (s *DapServer) run() {
+	exitCode := 0
+	defer func() {
+		if e := recover(); e != nil {
+			s.event("output", map[string]interface{}{
+				"category": "stderr",
+				"output":   fmt.Sprintln(e),
+			})
+			exitCode = 1
+		}
+		s.event("terminated", nil)
+		s.event("exited", map[string]interface{}{"exitCode": exitCode})
+	}()
+
+	env := &RuntimeEnv{
+		stdin:  strings.NewReader(""),
+		stdout: &dapOutput{s, "stdout"},
+		stderr: &dapOutput{s, "stderr"},
+		caps:   CapDefault,
+	}
+	exitCode = exitStatus(env, s.debugger.Run(context.Background(), NewRuntime(env), s.prog))
+}
+
+// Paused records the program's state for later inspection by the client,
+// announces the stop and waits for the client to resume execution.
+func (s *DapServer) Paused(d *Debugger, r *Runtime, n AstStmtNode, reason string) DebugAction {
+	var frames []dapFrame
+	for _, f := range r.Frames() {
+		frame := dapFrame{name: f.Name, line: f.Pos.Line, col: f.Pos.Col}
+		for _, name := range sortedVars(f.Scope) {
+			e, _ := f.Scope.GetVar(name)
+			frame.vars = append(frame.vars, map[string]interface{}{
+				"name":               name,
+				"value":              formatValue(e),
+				"type":               dataTypeName(e.DataType),
+				"variablesReference": 0,
+			})
+		}
+		frames = append(frames, frame)
+	}
+	s.mu.Lock()
+	s.frames = frames
+	s.paused = true
+	s.mu.Unlock()
+
+	s.event("stopped", map[string]interface{}{
+		"reason":            reason,
+		"threadId":          1,
+		"allThreadsStopped": true,
+	})
+	return <-s.resume
+}
+
+// dapOutput forwards the program's writes to the client as output events.
+type dapOutput struct {
+	s        *DapServer
+	category string
+}
+
+func (o *dapOutput) Write(b []byte) (int, error) {
+	o.s.event("output", map[string]interface{}{
+		"category": o.category,
+		"output":   string(b),
+	})
+	return len(b), nil
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/textproto"
+	"os"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// dapClient drives a DapServer through a pair of pipes.
+type dapClient struct {
+	t   *testing.T
+	w   io.Writer
+	r   *bufio.Reader
+	seq int
+}
+
+func (c *dapClient) request(command string, args interface{}) {
+	c.seq++
+	body, _ := json.Marshal(map[string]interface{}{
+		"seq":       c.seq,
+		"type":      "request",
+		"command":   command,
+		"arguments": args,
+	})
+	fmt.Fprintf(c.w, "Content-Length: %d\r\n\r\n%s", len(body), body)
+}
+
+// expect reads messages until one of the given type and name (command or
+// event) arrives, and returns it.
+func (c *dapClient) expect(typ, name string) map[string]interface{} {
+	for {
+		header, err := textproto.NewReader(c.r).ReadMIMEHeader()
+		if err != nil {
+			c.t.Fatal(err)
+		}
+		length, _ := strconv.Atoi(header.Get("Content-Length"))
+		body := make([]byte, length)
+		io.ReadFull(c.r, body)
+
+		msg := map[string]interface{}{}
+		json.Unmarshal(body, &msg)
+		if msg["type"] == typ && (msg["command"] == name || msg["event"] == name) {
+			return msg
+		}
+	}
+}
+
+func TestDapServer(t *testing.T) {
+	t.Parallel()
+
+	fp, _ := ioutil.TempFile("", "kiwi")
+	defer os.Remove(fp.Name())
+	fp.WriteString(debugSource + "write(z)\nexit(z)\n")
+	fp.Close()
+
+	inR, inW := io.Pipe()
+	outR, outW := io.Pipe()
+	s := NewDapServer(inR, outW)
+	go s.Serve()
+	c := &dapClient{t: t, w: inW, r: bufio.NewReader(outR)}
+
+	c.request("initialize", map[string]interface{}{"adapterID": "kiwi"})
+	msg := c.expect("response", "initialize")
+	assert.Equal(t, true, msg["success"])
+	c.expect("event", "initialized")
+
+	c.request("launch", map[string]interface{}{"program": fp.Name()})
+	assert.Equal(t, true, c.expect("response", "launch")["success"])
+
+	c.request("setBreakpoints", map[string]interface{}{
+		"source":      map[string]interface{}{"path": fp.Name()},
+		"breakpoints": []map[string]interface{}{{"line": 3}},
+	})
+	c.expect("response", "setBreakpoints")
+
+	c.request("configurationDone", nil)
+	c.expect("response", "configurationDone")
+
+	msg = c.expect("event", "stopped")
+	assert.Equal(t, "breakpoint", msg["body"].(map[string]interface{})["reason"])
+
+	c.request("stackTrace", map[string]interface{}{"threadId": 1})
+	msg = c.expect("response", "stackTrace")
+	frames := msg["body"].(map[string]interface{})["stackFrames"].([]interface{})
+	assert.Equal(t, 2, len(frames))
+	top := frames[0].(map[string]interface{})
+	assert.Equal(t, "double", top["name"])
+	assert.Equal(t, 3.0, top["line"])
+
+	c.request("variables", map[string]interface{}{
+		"variablesReference": top["id"].(float64) + 1,
+	})
+	msg = c.expect("response", "variables")
+	vars := msg["body"].(map[string]interface{})["variables"].([]interface{})
+	assert.Equal(t, "m", vars[0].(map[string]interface{})["name"])
+	assert.Equal(t, "2", vars[0].(map[string]interface{})["value"])
+
+	c.request("stepOut", map[string]interface{}{"threadId": 1})
+	msg = c.expect("event", "stopped")
+	assert.Equal(t, "step", msg["body"].(map[string]interface{})["reason"])
+
+	c.request("continue", map[string]interface{}{"threadId": 1})
+	msg = c.expect("event", "output")
+	assert.Equal(t, "3", msg["body"].(map[string]interface{})["output"])
+	c.expect("event", "terminated")
+	msg = c.expect("event", "exited")
+	assert.Equal(t, 3.0, msg["body"].(map[string]interface{})["exitCode"])
+
+	c.request("disconnect", nil)
+	c.expect("response", "disconnect")
+}
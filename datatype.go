@@ -10,4 +10,31 @@ const (
 	TypFunc
 	TypNumber
 	TypString
+	TypDecimal
+	TypList
+	TypMap
+	TypNull
 )
+
+// dataTypeName returns the name by which Kiwi programs refer to t.
+func dataTypeName(t DataType) string {
+	switch t {
+	case TypBool:
+		return "bool"
+	case TypNumber:
+		return "num"
+	case TypDecimal:
+		return "dec"
+	case TypString:
+		return "str"
+	case TypList:
+		return "list"
+	case TypMap:
+		return "map"
+	case TypNull:
+		return "null"
+	case TypFunc, TypBuiltin:
+		return "func"
+	}
+	return "unknown"
+}
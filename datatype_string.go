@@ -4,9 +4,9 @@ package main
 
 import "strconv"
 
-const _DataType_name = "TypUnknownTypBuiltinTypBoolTypFuncTypNumberTypString"
+const _DataType_name = "TypUnknownTypBuiltinTypBoolTypFuncTypNumberTypStringTypDecimalTypListTypMapTypNull"
 
-var _DataType_index = [...]uint8{0, 10, 20, 27, 34, 43, 52}
+var _DataType_index = [...]uint8{0, 10, 20, 27, 34, 43, 52, 62, 69, 75, 82}
 
 func (i DataType) String() string {
 	if i >= DataType(len(_DataType_index)-1) {
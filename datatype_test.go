@@ -17,6 +17,10 @@ func TestDataType(t *testing.T) {
 			TypFunc:       "TypFunc",
 			TypNumber:     "TypNumber",
 			TypString:     "TypString",
+			TypDecimal:    "TypDecimal",
+			TypList:       "TypList",
+			TypMap:        "TypMap",
+			TypNull:       "TypNull",
 			DataType(255): "DataType(255)",
 		}
 
@@ -24,4 +28,13 @@ func TestDataType(t *testing.T) {
 			assert.Equal(t, str, typ.String())
 		}
 	})
+
+	t.Run("Test type names", func(t *testing.T) {
+		assert.Equal(t, "num", dataTypeName(TypNumber))
+		assert.Equal(t, "list", dataTypeName(TypList))
+		assert.Equal(t, "map", dataTypeName(TypMap))
+		assert.Equal(t, "null", dataTypeName(TypNull))
+		assert.Equal(t, "func", dataTypeName(TypBuiltin))
+		assert.Equal(t, "unknown", dataTypeName(TypUnknown))
+	})
 }
@@ -0,0 +1,330 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// DebugAction tells a Debugger how to resume a paused program.
+type DebugAction int
+
+const (
+	// DebugContinue runs until the next breakpoint.
+	DebugContinue DebugAction = iota
+	// DebugStepInto pauses at the very next statement.
+	DebugStepInto
+	// DebugStepOver pauses at the next statement in the same or an outer
+	// frame.
+	DebugStepOver
+	// DebugStepOut pauses at the next statement in an outer frame.
+	DebugStepOut
+	// DebugQuit abandons the program.
+	DebugQuit
+)
+
+// ErrDebugQuit is raised through the runtime when the user ends a debugging
+// session before the program has finished.
+var ErrDebugQuit = errors.New("debugging session ended")
+
+// DebugFrontend presents a paused program to the user and decides how it
+// resumes. reason is one of "entry", "breakpoint", "step" or "pause".
+type DebugFrontend interface {
+	Paused(d *Debugger, r *Runtime, n AstStmtNode, reason string) DebugAction
+}
+
+// Debugger implements the Tracer interface to pause a running program at
+// breakpoints and while stepping, handing control to a DebugFrontend.
+type Debugger struct {
+	mu          sync.Mutex
+	breakpoints map[int]bool
+	action      DebugAction
+	depth       int
+	started     bool
+	pause       bool
+	frontend    DebugFrontend
+}
+
+// NewDebugger returns a debugger that reports to f. When stopOnEntry is set
+// the program pauses before its first statement.
+func NewDebugger(f DebugFrontend, stopOnEntry bool) *Debugger {
+	d := &Debugger{
+		breakpoints: make(map[int]bool),
+		action:      DebugContinue,
+		frontend:    f,
+	}
+	if stopOnEntry {
+		d.action = DebugStepInto
+	}
+	return d
+}
+
+// SetBreakpoint pauses the program whenever a statement on line begins.
+func (d *Debugger) SetBreakpoint(line int) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.breakpoints[line] = true
+}
+
+// ClearBreakpoint removes the breakpoint on line, if any.
+func (d *Debugger) ClearBreakpoint(line int) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	delete(d.breakpoints, line)
+}
+
+// ClearBreakpoints removes all breakpoints.
+func (d *Debugger) ClearBreakpoints() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.breakpoints = make(map[int]bool)
+}
+
+// Breakpoints returns the lines with breakpoints in ascending order.
+func (d *Debugger) Breakpoints() []int {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	var lines []int
+	for line := range d.breakpoints {
+		lines = append(lines, line)
+	}
+	sort.Ints(lines)
+	return lines
+}
+
+// Pause asks the debugger to pause the program at its next statement.
+func (d *Debugger) Pause() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.pause = true
+}
+
+// Stop asks the debugger to abandon the program at its next statement.
+func (d *Debugger) Stop() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.action = DebugQuit
+}
+
+// Run executes prog on r under the debugger's control until it finishes or
+// ctx is done, returning any error raised by the program as Runtime.Run
+// does. A session ended by the user is not reported as an error.
+func (d *Debugger) Run(ctx context.Context, r *Runtime, prog *AstProgramNode) error {
+	r.AddTracer(d)
+	if err := r.Run(ctx, prog); err != ErrDebugQuit {
+		return err
+	}
+	return nil
+}
+
+// Stmt pauses before n when it is on a breakpoint line or when the current
+// step has completed.
+func (d *Debugger) Stmt(r *Runtime, n AstStmtNode) {
+	depth := len(r.Frames())
+
+	d.mu.Lock()
+	reason := ""
+	switch {
+	case d.action == DebugQuit:
+		d.mu.Unlock()
+		panic(ErrDebugQuit)
+	case d.action == DebugStepInto,
+		d.action == DebugStepOver && depth <= d.depth,
+		d.action == DebugStepOut && depth < d.depth:
+		reason = "step"
+	}
+	if d.breakpoints[n.Position().Line] {
+		reason = "breakpoint"
+	}
+	if d.pause {
+		d.pause = false
+		reason = "pause"
+	}
+	if !d.started {
+		d.started = true
+		if reason != "" {
+			reason = "entry"
+		}
+	}
+	d.mu.Unlock()
+
+	if reason == "" {
+		return
+	}
+	action := d.frontend.Paused(d, r, n, reason)
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if action == DebugQuit {
+		panic(ErrDebugQuit)
+	}
+	d.action, d.depth = action, depth
+}
+
+// Enter is part of the Tracer interface; function calls are tracked
+// through the runtime's frames instead.
+func (d *Debugger) Enter(r *Runtime, f *Frame) {}
+
+// Leave is part of the Tracer interface.
+func (d *Debugger) Leave(r *Runtime, f *Frame) {}
+
+// formatValue presents a runtime value the way it would be written in
+// source.
+func formatValue(e ScopeEntry) string {
+	switch e.DataType {
+	case TypString:
+		return strconv.Quote(e.Value.(string))
+	case TypNumber:
+		return numToStr(e.Value)
+	case TypDecimal:
+		return e.Value.(Decimal).String() + "d"
+	case TypBool:
+		return strconv.FormatBool(e.Value.(bool))
+	case TypList, TypMap, TypNull:
+		return castStr(nil, e)
+	}
+	return fmt.Sprint(e.Value)
+}
+
+// sortedVars returns the names of the variables defined in s in
+// alphabetical order.
+func sortedVars(s *Scope) []string {
+	var names []string
+	if s == nil {
+		return names
+	}
+	for name := range s.vars {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// DebugConsole is a line-oriented DebugFrontend that reads commands from in
+// and writes to out.
+type DebugConsole struct {
+	in     *bufio.Scanner
+	out    io.Writer
+	source []string
+}
+
+// NewDebugConsole returns a console for debugging the program whose source
+// lines are given in source.
+func NewDebugConsole(in io.Reader, out io.Writer, source []string) *DebugConsole {
+	return &DebugConsole{bufio.NewScanner(in), out, source}
+}
+
+const debugConsoleHelp = `commands:
+  c, continue      run until the next breakpoint
+  s, step          step into the next statement
+  n, next          step over function calls
+  o, out           run until the current function returns
+  b, break LINE    set a breakpoint
+  clear [LINE]     remove one or all breakpoints
+  p, print [NAME]  show one or all variables in the current frame
+  bt, stack        show the call stack
+  l, list          show the source around the current line
+  q, quit          end the session
+`
+
+// Paused shows where the program stopped and processes commands until one
+// resumes execution.
+func (c *DebugConsole) Paused(d *Debugger, r *Runtime, n AstStmtNode, reason string) DebugAction {
+	pos := n.Position()
+	fmt.Fprintf(c.out, "%s at line %d\n", reason, pos.Line)
+	c.list(pos.Line, 0)
+
+	frames := r.Frames()
+	frame := frames[len(frames)-1]
+	for {
+		fmt.Fprint(c.out, "(kiwi) ")
+		if !c.in.Scan() {
+			fmt.Fprintln(c.out)
+			return DebugQuit
+		}
+		fields := strings.Fields(c.in.Text())
+		if len(fields) == 0 {
+			continue
+		}
+		switch fields[0] {
+		case "c", "continue":
+			return DebugContinue
+		case "s", "step":
+			return DebugStepInto
+		case "n", "next":
+			return DebugStepOver
+		case "o", "out":
+			return DebugStepOut
+		case "q", "quit":
+			return DebugQuit
+		case "b", "break":
+			for _, f := range fields[1:] {
+				line, err := strconv.Atoi(f)
+				if err != nil || line < 1 {
+					fmt.Fprintf(c.out, "invalid line %q\n", f)
+					continue
+				}
+				d.SetBreakpoint(line)
+				fmt.Fprintf(c.out, "breakpoint set at line %d\n", line)
+			}
+			if len(fields) == 1 {
+				fmt.Fprintln(c.out, "breakpoints:", d.Breakpoints())
+			}
+		case "clear":
+			if len(fields) == 1 {
+				d.ClearBreakpoints()
+			}
+			for _, f := range fields[1:] {
+				if line, err := strconv.Atoi(f); err == nil {
+					d.ClearBreakpoint(line)
+				}
+			}
+		case "p", "print":
+			names := fields[1:]
+			if len(names) == 0 {
+				names = sortedVars(frame.Scope)
+			}
+			for _, name := range names {
+				if frame.Scope == nil {
+					break
+				}
+				if e, ok := frame.Scope.GetVar(name); ok {
+					fmt.Fprintf(c.out, "%s = %s\n", name, formatValue(e))
+				} else {
+					fmt.Fprintf(c.out, "%s is not defined\n", name)
+				}
+			}
+		case "bt", "stack":
+			for i := len(frames) - 1; i >= 0; i-- {
+				fmt.Fprintf(c.out, "#%d %s at line %d\n",
+					len(frames)-1-i, frames[i].Name, frames[i].Pos.Line)
+			}
+		case "l", "list":
+			c.list(pos.Line, 5)
+		case "h", "help":
+			fmt.Fprint(c.out, debugConsoleHelp)
+		default:
+			fmt.Fprintf(c.out, "unknown command %q, try help\n", fields[0])
+		}
+	}
+}
+
+// list prints the source lines within context lines of line.
+func (c *DebugConsole) list(line, context int) {
+	for i := line - context; i <= line+context; i++ {
+		if i < 1 || i > len(c.source) {
+			continue
+		}
+		marker := " "
+		if i == line {
+			marker = ">"
+		}
+		fmt.Fprintf(c.out, "%s %4d  %s\n", marker, i, c.source[i-1])
+	}
+}
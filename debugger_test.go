@@ -0,0 +1,120 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const debugSource = `func double n {
+  m := n * 2
+  return m
+}
+x := 1
+y := double(x)
+z := y + 1
+`
+
+func debugSession(commands string, stopOnEntry bool, bps ...int) string {
+	prog, _ := newParser(debugSource).Parse()
+	out := &bytes.Buffer{}
+	console := NewDebugConsole(strings.NewReader(commands), out,
+		strings.Split(debugSource, "\n"))
+	d := NewDebugger(console, stopOnEntry)
+	for _, line := range bps {
+		d.SetBreakpoint(line)
+	}
+	d.Run(context.Background(), NewRuntime(testRuntimeEnv("")), prog)
+	return out.String()
+}
+
+func TestDebugger(t *testing.T) {
+	t.Parallel()
+
+	t.Run("Pause on entry", func(t *testing.T) {
+		out := debugSession("c\n", true)
+		assert.Contains(t, out, "entry at line 1\n>    1  func double n {\n")
+	})
+
+	t.Run("Pause at breakpoint", func(t *testing.T) {
+		out := debugSession("p\nbt\nc\n", false, 3)
+		assert.Contains(t, out, "breakpoint at line 3\n")
+		assert.Contains(t, out, "m = 2\nn = 1\n")
+		assert.Contains(t, out, "#0 double at line 3\n#1 main at line 6\n")
+	})
+
+	t.Run("Set breakpoint from console", func(t *testing.T) {
+		out := debugSession("b 7\nc\np y\nc\n", true)
+		assert.Contains(t, out, "breakpoint set at line 7\n")
+		assert.Contains(t, out, "breakpoint at line 7\n")
+		assert.Contains(t, out, "y = 2\n")
+	})
+
+	t.Run("Step into", func(t *testing.T) {
+		out := debugSession("s\ns\ns\nc\n", false, 5)
+		assert.Contains(t, out, "step at line 6\n")
+		assert.Contains(t, out, "step at line 2\n")
+		assert.Contains(t, out, "step at line 3\n")
+	})
+
+	t.Run("Step over", func(t *testing.T) {
+		out := debugSession("n\nn\nc\n", false, 5)
+		assert.Contains(t, out, "step at line 6\n")
+		assert.Contains(t, out, "step at line 7\n")
+		assert.NotContains(t, out, "step at line 2\n")
+	})
+
+	t.Run("Step out", func(t *testing.T) {
+		out := debugSession("o\nc\n", false, 2)
+		assert.Contains(t, out, "breakpoint at line 2\n")
+		assert.Contains(t, out, "step at line 7\n")
+		assert.NotContains(t, out, "step at line 3\n")
+	})
+
+	t.Run("Quit", func(t *testing.T) {
+		prog, _ := newParser(debugSource).Parse()
+		console := NewDebugConsole(strings.NewReader("q\n"), &bytes.Buffer{}, nil)
+		r := NewRuntime(testRuntimeEnv(""))
+		assert.Nil(t, NewDebugger(console, true).Run(context.Background(), r, prog))
+		_, ok := prog.Scope.GetVar("x")
+		assert.False(t, ok)
+	})
+
+	t.Run("Run as the runtime does", func(t *testing.T) {
+		console := NewDebugConsole(strings.NewReader("c\n"), &bytes.Buffer{}, nil)
+		run := func(src string, env *RuntimeEnv) error {
+			prog, err := newParser(src).Parse()
+			assert.Nil(t, err)
+			return NewDebugger(console, false).Run(context.Background(), NewRuntime(env), prog)
+		}
+
+		assert.Equal(t, &ExitError{4}, run("exit(4)", testRuntimeEnv("")))
+		assert.EqualError(t, run("x := y", testRuntimeEnv("")), "variable is not defined")
+
+		env := testRuntimeEnv("")
+		env.maxDepth = 5
+		assert.Equal(t, ErrDepthLimit, run("func f { f() }\nf()", env))
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		prog, _ := newParser("while true { }").Parse()
+		err := NewDebugger(console, false).Run(ctx, NewRuntime(testRuntimeEnv("")), prog)
+		assert.Equal(t, &InterruptError{Pos{1, 1}, context.Canceled}, err)
+	})
+
+	t.Run("Unknown command", func(t *testing.T) {
+		out := debugSession("frobnicate\nc\n", true)
+		assert.Contains(t, out, `unknown command "frobnicate"`)
+	})
+
+	t.Run("Format values", func(t *testing.T) {
+		assert.Equal(t, `"a\nb"`, formatValue(ScopeEntry{TypString, "a\nb"}))
+		assert.Equal(t, "2.5", formatValue(ScopeEntry{TypNumber, 2.5}))
+		assert.Equal(t, "true", formatValue(ScopeEntry{TypBool, true}))
+		assert.Equal(t, `["a",null]`, formatValue(ScopeEntry{TypList,
+			[]ScopeEntry{{TypString, "a"}, {TypNull, nil}}}))
+	})
+}
@@ -0,0 +1,269 @@
+package main
+
+import (
+	"errors"
+	"math"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+// RoundingMode selects how the quotient of a decimal division is rounded to
+// the scale of its result.
+type RoundingMode uint
+
+const (
+	RoundHalfEven RoundingMode = iota // to nearest, ties to even
+	RoundHalfUp                       // to nearest, ties away from zero
+	RoundHalfDown                     // to nearest, ties toward zero
+	RoundUp                           // away from zero
+	RoundDown                         // toward zero
+	RoundCeiling                      // toward positive infinity
+	RoundFloor                        // toward negative infinity
+)
+
+// roundingModes are the rounding modes by the names a program gives them.
+var roundingModes = map[string]RoundingMode{
+	"half_even": RoundHalfEven,
+	"half_up":   RoundHalfUp,
+	"half_down": RoundHalfDown,
+	"up":        RoundUp,
+	"down":      RoundDown,
+	"ceiling":   RoundCeiling,
+	"floor":     RoundFloor,
+}
+
+// Decimal is an exact base-10 number, with the value unscaled × 10^-scale.
+// The scale is the number of digits it has after the decimal point, which
+// are all shown when it is formatted.
+type Decimal struct {
+	unscaled *big.Int
+	scale    int
+}
+
+var bigTen = big.NewInt(10)
+
+// pow10 returns 10^n.
+func pow10(n int) *big.Int {
+	return new(big.Int).Exp(bigTen, big.NewInt(int64(n)), nil)
+}
+
+// parseDec converts the string s, an optionally signed sequence of digits
+// with an optional fractional part, to a decimal, reporting whether it is
+// valid.
+func parseDec(s string) (Decimal, bool) {
+	digits := s
+	if strings.HasPrefix(digits, "-") || strings.HasPrefix(digits, "+") {
+		digits = digits[1:]
+	}
+	scale := 0
+	if i := strings.IndexByte(digits, '.'); i >= 0 {
+		scale = len(digits) - i - 1
+		digits = digits[:i] + digits[i+1:]
+	}
+	if digits == "" || strings.Trim(digits, "0123456789") != "" {
+		return Decimal{new(big.Int), 0}, false
+	}
+	u, _ := new(big.Int).SetString(digits, 10)
+	if strings.HasPrefix(s, "-") {
+		u.Neg(u)
+	}
+	return Decimal{u, scale}, true
+}
+
+// parseDecLit converts the literal lit, without its "d" suffix, to a
+// decimal. It is written like a number literal, but in base 10 and without
+// an exponent.
+func parseDecLit(lit string) (Decimal, error) {
+	if _, err := parseNumLit(lit); err != nil {
+		return Decimal{}, err
+	}
+	lower := strings.ToLower(lit)
+	if strings.HasPrefix(lower, "0x") || strings.HasPrefix(lower, "0o") ||
+		strings.HasPrefix(lower, "0b") {
+		return Decimal{}, errors.New("decimal literal must be written in base 10")
+	}
+	if strings.ContainsRune(lower, 'e') {
+		return Decimal{}, errors.New("decimal literal may not have an exponent")
+	}
+	d, _ := parseDec(strings.Replace(lit, "_", "", -1))
+	return d, nil
+}
+
+// numToDec converts the number v to a decimal. Floats convert to the
+// decimal with the fewest digits that represents them exactly, and cannot
+// be infinite or NaN.
+func numToDec(v interface{}) (Decimal, bool) {
+	if isInt(v) {
+		return Decimal{toBig(v), 0}, true
+	}
+	f := v.(float64)
+	if math.IsInf(f, 0) || math.IsNaN(f) {
+		return Decimal{new(big.Int), 0}, false
+	}
+	return parseDec(strconv.FormatFloat(f, 'f', -1, 64))
+}
+
+// toNum converts d to a number, which is an integer if d is whole.
+func (d Decimal) toNum() interface{} {
+	r := new(big.Rat).SetFrac(d.unscaled, pow10(d.scale))
+	if r.IsInt() {
+		return normInt(r.Num())
+	}
+	f, _ := r.Float64()
+	return f
+}
+
+func (d Decimal) String() string {
+	digits := new(big.Int).Abs(d.unscaled).String()
+	if d.scale > 0 {
+		if len(digits) <= d.scale {
+			digits = strings.Repeat("0", d.scale-len(digits)+1) + digits
+		}
+		i := len(digits) - d.scale
+		digits = digits[:i] + "." + digits[i:]
+	}
+	if d.unscaled.Sign() < 0 {
+		return "-" + digits
+	}
+	return digits
+}
+
+// align returns the unscaled values of d and e at the larger of their
+// scales, and that scale.
+func (d Decimal) align(e Decimal) (x, y *big.Int, scale int) {
+	x, y = d.unscaled, e.unscaled
+	switch {
+	case d.scale < e.scale:
+		x = new(big.Int).Mul(x, pow10(e.scale-d.scale))
+		return x, y, e.scale
+	case d.scale > e.scale:
+		y = new(big.Int).Mul(y, pow10(d.scale-e.scale))
+	}
+	return x, y, d.scale
+}
+
+func (d Decimal) Add(e Decimal) Decimal {
+	x, y, scale := d.align(e)
+	return Decimal{new(big.Int).Add(x, y), scale}
+}
+
+func (d Decimal) Sub(e Decimal) Decimal {
+	x, y, scale := d.align(e)
+	return Decimal{new(big.Int).Sub(x, y), scale}
+}
+
+func (d Decimal) Mul(e Decimal) Decimal {
+	return Decimal{new(big.Int).Mul(d.unscaled, e.unscaled), d.scale + e.scale}
+}
+
+func (d Decimal) Neg() Decimal {
+	return Decimal{new(big.Int).Neg(d.unscaled), d.scale}
+}
+
+func (d Decimal) Abs() Decimal {
+	return Decimal{new(big.Int).Abs(d.unscaled), d.scale}
+}
+
+func (d Decimal) IsZero() bool {
+	return d.unscaled.Sign() == 0
+}
+
+// Pow returns d raised to the non-negative power n. It panics if the result
+// would be too large to compute.
+func (d Decimal) Pow(n int64) Decimal {
+	if d.unscaled.BitLen() > 1 && n > maxPowBits/int64(d.unscaled.BitLen()) ||
+		d.scale > 0 && n > maxPowBits/int64(d.scale) {
+		panic("decimal power too large")
+	}
+	u := new(big.Int).Exp(d.unscaled, big.NewInt(n), nil)
+	return Decimal{u, d.scale * int(n)}
+}
+
+// Cmp compares d and e by value, returning -1, 0 or +1.
+func (d Decimal) Cmp(e Decimal) int {
+	x, y, _ := d.align(e)
+	return x.Cmp(y)
+}
+
+// Quo returns d divided by e, rounded to the given scale with mode. It
+// panics if e is zero.
+func (d Decimal) Quo(e Decimal, scale int, mode RoundingMode) Decimal {
+	if e.IsZero() {
+		panic("division by zero")
+	}
+	// scale the dividend so the integer quotient has the result's scale
+	num := d.unscaled
+	if shift := scale + e.scale - d.scale; shift > 0 {
+		num = new(big.Int).Mul(num, pow10(shift))
+	} else if shift < 0 {
+		e = Decimal{new(big.Int).Mul(e.unscaled, pow10(-shift)), e.scale}
+	}
+	q, r := new(big.Int).QuoRem(num, e.unscaled, new(big.Int))
+	if r.Sign() == 0 {
+		return Decimal{q, scale}
+	}
+
+	sign := num.Sign() * e.unscaled.Sign()
+	half := new(big.Int).Mul(new(big.Int).Abs(r), big.NewInt(2)).
+		Cmp(new(big.Int).Abs(e.unscaled))
+	away := false
+	switch mode {
+	case RoundHalfEven:
+		away = half > 0 || half == 0 && q.Bit(0) == 1
+	case RoundHalfUp:
+		away = half >= 0
+	case RoundHalfDown:
+		away = half > 0
+	case RoundUp:
+		away = true
+	case RoundCeiling:
+		away = sign > 0
+	case RoundFloor:
+		away = sign < 0
+	}
+	if away {
+		q.Add(q, big.NewInt(int64(sign)))
+	}
+	return Decimal{q, scale}
+}
+
+// Round returns d rounded to the given scale with mode.
+func (d Decimal) Round(scale int, mode RoundingMode) Decimal {
+	return d.Quo(Decimal{big.NewInt(1), 0}, scale, mode)
+}
+
+// Rem returns the remainder of dividing d by e, which has the sign of d. It
+// panics if e is zero.
+func (d Decimal) Rem(e Decimal) Decimal {
+	if e.IsZero() {
+		panic("division by zero")
+	}
+	x, y, scale := d.align(e)
+	return Decimal{new(big.Int).Rem(x, y), scale}
+}
+
+// decOperands returns the operands of an operation on decimals. An integer
+// number is converted to a decimal, but ok is false if neither operand is
+// a decimal or the other is not a decimal or integer.
+func decOperands(left, right ScopeEntry) (a, b Decimal, ok bool) {
+	if left.DataType != TypDecimal && right.DataType != TypDecimal {
+		return a, b, false
+	}
+	a, ok = decOperand(left)
+	if !ok {
+		return a, b, false
+	}
+	b, ok = decOperand(right)
+	return a, b, ok
+}
+
+func decOperand(e ScopeEntry) (Decimal, bool) {
+	switch {
+	case e.DataType == TypDecimal:
+		return e.Value.(Decimal), true
+	case e.DataType == TypNumber && isInt(e.Value):
+		return Decimal{toBig(e.Value), 0}, true
+	}
+	return Decimal{}, false
+}
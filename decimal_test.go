@@ -0,0 +1,131 @@
+package main
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func dec(s string) Decimal {
+	d, ok := parseDec(s)
+	if !ok {
+		panic("invalid decimal " + s)
+	}
+	return d
+}
+
+func TestDecimal(t *testing.T) {
+	t.Parallel()
+
+	t.Run("Parse and format", func(t *testing.T) {
+		for _, s := range []string{"0", "19.99", "-0.05", "100.00", "0.000"} {
+			assert.Equal(t, s, dec(s).String())
+		}
+		assert.Equal(t, "1.5", dec("+1.5").String())
+		assert.Equal(t, "0.50", dec(".50").String())
+
+		for _, s := range []string{"", "-", "1.2.3", "1e5", "abc"} {
+			_, ok := parseDec(s)
+			assert.False(t, ok, s)
+		}
+	})
+
+	t.Run("Parse literals", func(t *testing.T) {
+		d, err := parseDecLit("1_000.50")
+		assert.Nil(t, err)
+		assert.Equal(t, "1000.50", d.String())
+
+		_, err = parseDecLit("0xFF")
+		assert.EqualError(t, err, "decimal literal must be written in base 10")
+		_, err = parseDecLit("1e3")
+		assert.EqualError(t, err, "decimal literal may not have an exponent")
+		_, err = parseDecLit("1__0")
+		assert.EqualError(t, err, "'_' must separate successive digits")
+	})
+
+	t.Run("Add and subtract exactly", func(t *testing.T) {
+		assert.Equal(t, "0.3", dec("0.1").Add(dec("0.2")).String())
+		assert.Equal(t, "21.49", dec("19.99").Add(dec("1.5")).String())
+		assert.Equal(t, "-0.01", dec("19.99").Sub(dec("20")).String())
+	})
+
+	t.Run("Multiply", func(t *testing.T) {
+		assert.Equal(t, "59.97", dec("19.99").Mul(dec("3")).String())
+		assert.Equal(t, "1.0000", dec("1.25").Mul(dec("0.80")).String())
+	})
+
+	t.Run("Divide with rounding modes", func(t *testing.T) {
+		tests := []struct {
+			mode     RoundingMode
+			pos, neg string
+		}{
+			{RoundHalfEven, "0.12", "-0.12"},
+			{RoundHalfUp, "0.13", "-0.13"},
+			{RoundHalfDown, "0.12", "-0.12"},
+			{RoundUp, "0.13", "-0.13"},
+			{RoundDown, "0.12", "-0.12"},
+			{RoundCeiling, "0.13", "-0.12"},
+			{RoundFloor, "0.12", "-0.13"},
+		}
+		for _, test := range tests {
+			// 0.125 is a tie at two digits
+			assert.Equal(t, test.pos, dec("1").Quo(dec("8"), 2, test.mode).String())
+			assert.Equal(t, test.neg, dec("-1").Quo(dec("8"), 2, test.mode).String())
+		}
+
+		assert.Equal(t, "0.14", dec("1.13").Quo(dec("8"), 2, RoundHalfEven).String())
+		assert.Equal(t, "0.33", dec("1").Quo(dec("3"), 2, RoundHalfUp).String())
+		assert.Equal(t, "0.67", dec("2").Quo(dec("3"), 2, RoundHalfDown).String())
+		assert.Equal(t, "2.50", dec("10.00").Quo(dec("4"), 2, RoundHalfEven).String())
+		assert.Equal(t, "3", dec("10.00").Quo(dec("3.00"), 0, RoundDown).String())
+		assert.PanicsWithValue(t, "division by zero", func() {
+			dec("1").Quo(dec("0.00"), 2, RoundHalfEven)
+		})
+	})
+
+	t.Run("Take remainders", func(t *testing.T) {
+		assert.Equal(t, "1.5", dec("7.5").Rem(dec("2")).String())
+		assert.Equal(t, "-1.5", dec("-7.5").Rem(dec("2")).String())
+		assert.PanicsWithValue(t, "division by zero", func() {
+			dec("1").Rem(dec("0"))
+		})
+	})
+
+	t.Run("Raise to powers", func(t *testing.T) {
+		assert.Equal(t, "2.25", dec("1.5").Pow(2).String())
+		assert.Equal(t, "1", dec("0.5").Pow(0).String())
+		assert.Equal(t, "-0.001", dec("-0.1").Pow(3).String())
+		assert.PanicsWithValue(t, "decimal power too large", func() {
+			dec("1.5").Pow(1 << 30)
+		})
+		assert.PanicsWithValue(t, "decimal power too large", func() {
+			dec("2").Pow(1 << 62)
+		})
+		assert.PanicsWithValue(t, "decimal power too large", func() {
+			dec("0.1").Pow(1 << 62)
+		})
+		assert.Equal(t, "-1", dec("-1").Pow(1<<62+1).String())
+	})
+
+	t.Run("Compare by value", func(t *testing.T) {
+		assert.Equal(t, 0, dec("1.50").Cmp(dec("1.5")))
+		assert.Equal(t, -1, dec("-2").Cmp(dec("1.5")))
+		assert.Equal(t, 1, dec("0.01").Cmp(dec("0")))
+	})
+
+	t.Run("Convert numbers", func(t *testing.T) {
+		d, ok := numToDec(0.1)
+		assert.True(t, ok)
+		assert.Equal(t, "0.1", d.String())
+
+		d, _ = numToDec(bigInt("18446744073709551616"))
+		assert.Equal(t, "18446744073709551616", d.String())
+
+		_, ok = numToDec(math.Inf(1))
+		assert.False(t, ok)
+
+		assert.Equal(t, int64(2), dec("2.00").toNum())
+		assert.Equal(t, 19.99, dec("19.99").toNum())
+	})
+}
@@ -0,0 +1,298 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// fmtVerbs maps the verbs format understands to the flags each accepts.
+// Only the f, e and s verbs accept a precision.
+var fmtVerbs = map[rune]string{
+	'd': "-+ 0,", // integer in decimal
+	'x': "-0",    // integer in hexadecimal, lower case
+	'X': "-0",    // integer in hexadecimal, upper case
+	'o': "-0",    // integer in octal
+	'b': "-0",    // integer in binary
+	'f': "-+ 0,", // number or decimal with a fixed number of fractional digits
+	'e': "-+ 0",  // number in scientific notation
+	's': "-",     // string
+	't': "-",     // bool
+	'v': "-",     // any value, as cast to a string
+}
+
+// fmtSpec is a parsed conversion specification, %[flags][width][.prec]verb.
+type fmtSpec struct {
+	flags string
+	width int
+	prec  int // -1 if absent
+	verb  rune
+}
+
+// sprintf formats args according to the format string f. Errors in the
+// format, or in an argument given for a verb, are returned; an argument's
+// error names its position among args, counting from 1.
+func sprintf(env *RuntimeEnv, f string, args params) (string, error) {
+	var buf strings.Builder
+	n := 0
+	for i := 0; i < len(f); i++ {
+		if f[i] != '%' {
+			buf.WriteByte(f[i])
+			continue
+		}
+		spec, size, err := parseFmtSpec(f[i+1:])
+		if err != nil {
+			return "", err
+		}
+		i += size
+		if spec.verb == '%' {
+			buf.WriteByte('%')
+			continue
+		}
+		if n == len(args) {
+			return "", fmt.Errorf("missing argument %d for %%%c", n+1, spec.verb)
+		}
+		env.checkSize(spec.width)
+		env.checkSize(spec.prec)
+		str, err := spec.format(env, args[n])
+		if err != nil {
+			return "", fmt.Errorf("argument %d: %v", n+1, err)
+		}
+		n++
+		env.checkSize(buf.Len() + len(str))
+		buf.WriteString(str)
+	}
+	if n < len(args) {
+		return "", fmt.Errorf("%d arguments given but the format uses %d", len(args), n)
+	}
+	return buf.String(), nil
+}
+
+// parseFmtSpec parses the specification that s begins with, which follows a
+// '%', and returns it with its length.
+func parseFmtSpec(s string) (fmtSpec, int, error) {
+	spec := fmtSpec{prec: -1}
+	i := 0
+	for i < len(s) && strings.IndexByte("-+ 0,", s[i]) >= 0 {
+		i++
+	}
+	spec.flags = s[:i]
+
+	var err error
+	start := i
+	for i < len(s) && s[i] >= '0' && s[i] <= '9' {
+		i++
+	}
+	if i > start {
+		if spec.width, err = strconv.Atoi(s[start:i]); err != nil {
+			return spec, 0, errors.New("width too large")
+		}
+	}
+	if i < len(s) && s[i] == '.' {
+		i++
+		start = i
+		for i < len(s) && s[i] >= '0' && s[i] <= '9' {
+			i++
+		}
+		spec.prec = 0
+		if i > start {
+			if spec.prec, err = strconv.Atoi(s[start:i]); err != nil {
+				return spec, 0, errors.New("precision too large")
+			}
+		}
+	}
+
+	if i == len(s) {
+		return spec, 0, errors.New("format ends with an incomplete verb")
+	}
+	verb, size := utf8.DecodeRuneInString(s[i:])
+	spec.verb = verb
+	if verb == '%' {
+		return spec, i + size, nil
+	}
+	allowed, ok := fmtVerbs[verb]
+	if !ok {
+		return spec, 0, fmt.Errorf("unknown verb %%%c", verb)
+	}
+	for _, flag := range spec.flags {
+		if !strings.ContainsRune(allowed, flag) {
+			return spec, 0, fmt.Errorf("%%%c does not take the '%c' flag", verb, flag)
+		}
+	}
+	if spec.prec >= 0 && !strings.ContainsRune("fes", verb) {
+		return spec, 0, fmt.Errorf("%%%c does not take a precision", verb)
+	}
+	return spec, i + size, nil
+}
+
+func (spec fmtSpec) has(flag rune) bool {
+	return strings.ContainsRune(spec.flags, flag)
+}
+
+// format formats the value e as spec directs.
+func (spec fmtSpec) format(env *RuntimeEnv, e ScopeEntry) (string, error) {
+	mismatch := func(want string) error {
+		return fmt.Errorf("%%%c expects %s, got %s", spec.verb, want, formatValue(e))
+	}
+
+	var neg bool
+	var body string
+	numeric := true
+	switch spec.verb {
+	case 'd', 'x', 'X', 'o', 'b':
+		b, ok := intArg(e)
+		if !ok {
+			return "", mismatch("an integer")
+		}
+		neg = b.Sign() < 0
+		base := map[rune]int{'d': 10, 'x': 16, 'X': 16, 'o': 8, 'b': 2}[spec.verb]
+		body = new(big.Int).Abs(b).Text(base)
+		if spec.verb == 'X' {
+			body = strings.ToUpper(body)
+		}
+	case 'f':
+		switch e.DataType {
+		case TypNumber:
+			neg, body = fixedNum(e.Value, spec.prec)
+		case TypDecimal:
+			d := e.Value.(Decimal)
+			if spec.prec >= 0 {
+				mode := RoundHalfEven
+				if env != nil {
+					mode = env.decRounding
+				}
+				d = d.Round(spec.prec, mode)
+			}
+			neg, body = d.unscaled.Sign() < 0, d.Abs().String()
+		default:
+			return "", mismatch("a number")
+		}
+	case 'e':
+		var f float64
+		switch e.DataType {
+		case TypNumber:
+			f = toFloat(e.Value)
+		case TypDecimal:
+			f = toFloat(e.Value.(Decimal).toNum())
+		default:
+			return "", mismatch("a number")
+		}
+		prec := spec.prec
+		if prec < 0 {
+			prec = 6
+		}
+		neg, body = math.Signbit(f) && !math.IsNaN(f), formatFloat(f, 'e', prec)
+	case 's':
+		if e.DataType != TypString {
+			return "", mismatch("a string")
+		}
+		body = e.Value.(string)
+		if spec.prec >= 0 && utf8.RuneCountInString(body) > spec.prec {
+			body = string([]rune(body)[:spec.prec])
+		}
+		numeric = false
+	case 't':
+		if e.DataType != TypBool {
+			return "", mismatch("a bool")
+		}
+		body = strconv.FormatBool(e.Value.(bool))
+		numeric = false
+	case 'v':
+		body = castStr(env, e)
+		numeric = false
+	}
+
+	if spec.has(',') {
+		body = groupThousands(body)
+	}
+	sign := ""
+	switch {
+	case neg:
+		sign = "-"
+	case spec.has('+'):
+		sign = "+"
+	case spec.has(' '):
+		sign = " "
+	}
+
+	pad := spec.width - utf8.RuneCountInString(sign+body)
+	switch {
+	case pad <= 0:
+		return sign + body, nil
+	case spec.has('-'):
+		return sign + body + strings.Repeat(" ", pad), nil
+	case spec.has('0') && numeric:
+		return sign + strings.Repeat("0", pad) + body, nil
+	}
+	return strings.Repeat(" ", pad) + sign + body, nil
+}
+
+// intArg returns the value of e as an integer, reporting whether e is a
+// number or decimal with a whole value.
+func intArg(e ScopeEntry) (*big.Int, bool) {
+	switch e.DataType {
+	case TypNumber:
+		if isInt(e.Value) {
+			return toBig(e.Value), true
+		}
+		f := e.Value.(float64)
+		if math.IsInf(f, 0) || f != math.Trunc(f) {
+			return nil, false
+		}
+		b, _ := new(big.Float).SetFloat64(f).Int(nil)
+		return b, true
+	case TypDecimal:
+		if v := e.Value.(Decimal).toNum(); isInt(v) {
+			return toBig(v), true
+		}
+	}
+	return nil, false
+}
+
+// fixedNum formats the magnitude of the number v with prec fractional
+// digits, or 6 if prec is negative, and reports whether v is negative.
+func fixedNum(v interface{}, prec int) (bool, string) {
+	if prec < 0 {
+		prec = 6
+	}
+	if isInt(v) {
+		b := toBig(v)
+		body := new(big.Int).Abs(b).String()
+		if prec > 0 {
+			body += "." + strings.Repeat("0", prec)
+		}
+		return b.Sign() < 0, body
+	}
+	f := v.(float64)
+	return math.Signbit(f) && !math.IsNaN(f), formatFloat(f, 'f', prec)
+}
+
+// formatFloat formats the magnitude of f, leaving its sign to the caller.
+func formatFloat(f float64, verb byte, prec int) string {
+	return strings.TrimPrefix(strconv.FormatFloat(math.Abs(f), verb, prec, 64), "+")
+}
+
+// groupThousands separates the digits of the integer part of the formatted
+// number s into groups of three with commas.
+func groupThousands(s string) string {
+	end := strings.IndexAny(s, ".e")
+	if end < 0 {
+		end = len(s)
+	}
+	digits := s[:end]
+	if strings.Trim(digits, "0123456789") != "" {
+		return s
+	}
+	var buf strings.Builder
+	for i, ch := range digits {
+		if i > 0 && (len(digits)-i)%3 == 0 {
+			buf.WriteByte(',')
+		}
+		buf.WriteRune(ch)
+	}
+	return buf.String() + s[end:]
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFormat(t *testing.T) {
+	t.Parallel()
+
+	t.Run("Format values", func(t *testing.T) {
+		tests := []struct {
+			format   string
+			arg      ScopeEntry
+			expected string
+		}{
+			{"%d", ScopeEntry{TypNumber, int64(-42)}, "-42"},
+			{"%d", ScopeEntry{TypNumber, 1e6}, "1000000"},
+			{"%d", ScopeEntry{TypNumber, bigInt("18446744073709551616")}, "18446744073709551616"},
+			{"%d", ScopeEntry{TypDecimal, dec("5.00")}, "5"},
+			{"%,d", ScopeEntry{TypNumber, int64(-1234567)}, "-1,234,567"},
+			{"%+d", ScopeEntry{TypNumber, int64(7)}, "+7"},
+			{"% d", ScopeEntry{TypNumber, int64(7)}, " 7"},
+			{"%x", ScopeEntry{TypNumber, int64(255)}, "ff"},
+			{"%X", ScopeEntry{TypNumber, int64(-255)}, "-FF"},
+			{"%o", ScopeEntry{TypNumber, int64(8)}, "10"},
+			{"%08b", ScopeEntry{TypNumber, int64(5)}, "00000101"},
+			{"%f", ScopeEntry{TypNumber, 0.1}, "0.100000"},
+			{"%.2f", ScopeEntry{TypNumber, 2.675}, "2.67"},
+			{"%.3f", ScopeEntry{TypNumber, int64(2)}, "2.000"},
+			{"%,.2f", ScopeEntry{TypNumber, 1234567.891}, "1,234,567.89"},
+			{"%.0f", ScopeEntry{TypNumber, math.Inf(-1)}, "-Inf"},
+			{"%f", ScopeEntry{TypDecimal, dec("19.990")}, "19.990"},
+			{"%.1f", ScopeEntry{TypDecimal, dec("0.25")}, "0.2"},
+			{"%.4f", ScopeEntry{TypDecimal, dec("-0.25")}, "-0.2500"},
+			{"%e", ScopeEntry{TypNumber, int64(1000000)}, "1.000000e+06"},
+			{"%.2e", ScopeEntry{TypNumber, -0.000123}, "-1.23e-04"},
+			{"%s", ScopeEntry{TypString, "kiwi"}, "kiwi"},
+			{"%.2s", ScopeEntry{TypString, "日本語"}, "日本"},
+			{"%t", ScopeEntry{TypBool, false}, "false"},
+			{"%v", ScopeEntry{TypDecimal, dec("1.50")}, "1.50"},
+			{"%v", ScopeEntry{TypNumber, 1e21}, "1000000000000000000000"},
+		}
+		for _, test := range tests {
+			str, err := sprintf(nil, test.format, params{test.arg})
+			assert.Nil(t, err, test.format)
+			assert.Equal(t, test.expected, str, test.format)
+		}
+	})
+
+	t.Run("Pad and align", func(t *testing.T) {
+		tests := []struct {
+			format   string
+			arg      ScopeEntry
+			expected string
+		}{
+			{"[%6d]", ScopeEntry{TypNumber, int64(-42)}, "[   -42]"},
+			{"[%-6d]", ScopeEntry{TypNumber, int64(-42)}, "[-42   ]"},
+			{"[%06d]", ScopeEntry{TypNumber, int64(-42)}, "[-00042]"},
+			{"[%-06d]", ScopeEntry{TypNumber, int64(-42)}, "[-42   ]"},
+			{"[%+08.2f]", ScopeEntry{TypNumber, 3.14159}, "[+0003.14]"},
+			{"[%5s]", ScopeEntry{TypString, "né"}, "[   né]"},
+			{"[%-5t]", ScopeEntry{TypBool, true}, "[true ]"},
+			{"[%2s]", ScopeEntry{TypString, "longer"}, "[longer]"},
+		}
+		for _, test := range tests {
+			str, err := sprintf(nil, test.format, params{test.arg})
+			assert.Nil(t, err, test.format)
+			assert.Equal(t, test.expected, str, test.format)
+		}
+	})
+
+	t.Run("Format several values", func(t *testing.T) {
+		str, err := sprintf(nil, "%s is %d%% done", params{
+			{TypString, "job"},
+			{TypNumber, int64(50)},
+		})
+		assert.Nil(t, err)
+		assert.Equal(t, "job is 50% done", str)
+	})
+
+	t.Run("Report errors", func(t *testing.T) {
+		tests := []struct {
+			format string
+			args   params
+			err    string
+		}{
+			{"%d", params{{TypString, "42"}}, `argument 1: %d expects an integer, got "42"`},
+			{"%s %d", params{{TypString, "a"}, {TypNumber, 1.5}}, "argument 2: %d expects an integer, got 1.5"},
+			{"%f", params{{TypBool, true}}, "argument 1: %f expects a number, got true"},
+			{"%s", params{{TypNumber, int64(1)}}, "argument 1: %s expects a string, got 1"},
+			{"%t", params{{TypString, "true"}}, `argument 1: %t expects a bool, got "true"`},
+			{"%d %d", params{{TypNumber, int64(1)}}, "missing argument 2 for %d"},
+			{"%d", params{{TypNumber, int64(1)}, {TypNumber, int64(2)}}, "2 arguments given but the format uses 1"},
+			{"%y", params{}, "unknown verb %y"},
+			{"100%", params{}, "format ends with an incomplete verb"},
+			{"%,x", params{}, "%x does not take the ',' flag"},
+			{"%+s", params{}, "%s does not take the '+' flag"},
+			{"%.2d", params{}, "%d does not take a precision"},
+			{"%99999999999999999999d", params{}, "width too large"},
+		}
+		for _, test := range tests {
+			_, err := sprintf(nil, test.format, test.args)
+			assert.EqualError(t, err, test.err, test.format)
+		}
+	})
+
+	t.Run("Limit size", func(t *testing.T) {
+		env := &RuntimeEnv{maxSize: 10}
+		assert.PanicsWithValue(t, ErrSizeLimit, func() {
+			sprintf(env, "%20d", params{{TypNumber, int64(1)}})
+		})
+	})
+}
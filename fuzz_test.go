@@ -0,0 +1,151 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"io/ioutil"
+	"math/rand"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fuzzSeeds are small programs exercising each construct of the language,
+// added to the seed corpus alongside the example and conformance programs.
+var fuzzSeeds = []string{
+	"+ - * / % := : = < <= > >= && & || | ~ ~= ( ) { } , ?",
+	"func if else return while true false `if ident",
+	`"abc" "" "\\\"\r\n\t\x" "broken`,
+	`"\x41\u00e9\U0001F600\0" r"C:\dir\" """
+	  two
+	    lines
+	  """ "\q"`,
+	`"a${b}c${ {d} }\${e}" "x${"${y}"}" r"${z}" "${`,
+	"// single1\n// single2 /**/ /* a /* nested */ comment */ /* broken",
+	"#!/usr/bin/env kiwi -A\n#!x // c",
+	"123 0.123 1.",
+	"foo := 42 + 73 * (1 - 2) / 3 % 4",
+	"if foo = true { bar := 1 } else baz { } else { }",
+	"func foo a b { return a + b } x := foo(1, 2)",
+	"func foo { while true { return } } foo() return",
+	"while i < 10 { i := i + 1 }",
+	"x := \"42\":num + true:num write(x:str, \"\\n\")",
+	"write(strlen(read()))",
+	`s := "héllo" write(s[0], s[-1], s[1:3], s[:-2], s[2:], s[:], s[(s[0:0]:num)], -s[9], "${s[1]}")`,
+	`s := trim(" héllo ") write(substr(s, 1, 2), index(s, "l"), split("a,b", ",", 1), join("-", s, upper(s)), replace(s, "l", "L", 1), repeat(s, 2), reverse(s), ord("é"), chr(233), ltrim(s, "h"))`,
+	`printf("%-8s|%+08.2f|%,d|%x|%e|%t|%v|%%\n", "a", 3.14159, 1048576, 255, 1.5d, true, 2)`,
+	`write(-2 ** 2 ** 3, 2 ** -1, 1.5d ** 3, sqrt(2 ** 64), floor(-1.5d), round(1e20), min(1, nan()), max(1.5d, 2d), atan2(1, pi()), isinf(inf()))`,
+	`seed(3) write(random(), randint(-5, 5), randint(0, 2 ** 70), choice(1, "a", 2d), shuffle("héllo"))`,
+	`t := parse_time("2024-02-29 13:04", "%Y-%m-%d %H:%M", "UTC") write(format_time(t + duration("1h30m"), "%a %e %b %I:%M %p %z %j %%"), format_duration(t % 86400000))`,
+	`m := json_decode("{\"a\": [1, 2.5, \"x\", null]}") write(json_encode(put(m, "b", split("p,q", ",")), 2), m["a"][-1] = null(), len(keys(m)), m["a"][1:])`,
+	`write(match(r"^\w+$", "kiwi"), find_all(r"\d+", "a1b22", 1), regex_replace(r"(?P<x>a)(b)", "abab", r"${x}$2$$"), regex_split(r",\s*", "a, b,c", 2))`,
+	`func f n { if n > 2 { exit(n) } f(n + 1) } write(args(), len(args())) f(0)`,
+}
+
+// addFuzzSeeds adds the seed programs and the example and conformance
+// programs to f's corpus.
+func addFuzzSeeds(f *testing.F) {
+	for _, seed := range fuzzSeeds {
+		f.Add(seed)
+	}
+	for _, dir := range goldenDirs {
+		files, _ := filepath.Glob(filepath.Join(dir, "*.kw"))
+		for _, file := range files {
+			src, err := ioutil.ReadFile(file)
+			if err != nil {
+				f.Fatal(err)
+			}
+			f.Add(string(src))
+		}
+	}
+}
+
+// fuzzTimeout is how long a fuzzed program may run before it is
+// interrupted.
+const fuzzTimeout = 100 * time.Millisecond
+
+// fuzzEnv returns an environment whose limits stop programs that would run
+// too long, recurse too deeply or build huge strings while being fuzzed. Its
+// generator has a fixed seed so that each input runs the same way.
+func fuzzEnv(out io.Writer) *RuntimeEnv {
+	return &RuntimeEnv{
+		stdin:    strings.NewReader("input"),
+		stdout:   out,
+		stderr:   out,
+		maxNodes: 10000,
+		maxDepth: 100,
+		maxSize:  4096,
+		caps:     CapStdio | CapProcess | CapRandom,
+		rng:      rand.New(rand.NewSource(1)),
+	}
+}
+
+// checkRecovered fails the fuzz test with a panic that escaped
+// Runtime.Run. Run returns the errors a program raises, so what escapes it
+// is a bug in the interpreter, usually a Go runtime error.
+func checkRecovered(t *testing.T, src string, e interface{}) {
+	if _, ok := e.(runtime.Error); ok {
+		t.Fatalf("runtime error for input %q: %v", src, e)
+	}
+	t.Fatalf("unexpected panic value for input %q: %#v", src, e)
+}
+
+func FuzzScanner(f *testing.F) {
+	addFuzzSeeds(f)
+	f.Fuzz(func(t *testing.T, src string) {
+		s := NewScanner(strings.NewReader(src))
+		// every rune is consumed by at most one token
+		for i := 0; i <= len(src)+1; i++ {
+			if tok, _ := s.Scan(); tok == TkEOF {
+				return
+			}
+		}
+		t.Fatalf("scanner did not reach EOF for input %q", src)
+	})
+}
+
+func FuzzParser(f *testing.F) {
+	addFuzzSeeds(f)
+	f.Fuzz(func(t *testing.T, src string) {
+		prog, err := newParser(src).Parse()
+		if err == nil && prog == nil {
+			t.Fatalf("no program and no error for input %q", src)
+		}
+	})
+}
+
+func FuzzRun(f *testing.F) {
+	addFuzzSeeds(f)
+	f.Fuzz(func(t *testing.T, src string) {
+		prog, err := newParser(src).Parse()
+		if err != nil {
+			return
+		}
+
+		out := &bytes.Buffer{}
+		r := NewRuntime(fuzzEnv(out))
+		ctx, cancel := context.WithTimeout(context.Background(), fuzzTimeout)
+		defer cancel()
+		defer func() {
+			if e := recover(); e != nil {
+				checkRecovered(t, src, e)
+			}
+		}()
+		if err := r.Run(ctx, prog); err != nil {
+			// a program stopped by an error is unwound, whatever it
+			// was doing
+			if len(r.frames) > 0 || r.scopeStack.Size() > 0 || r.stack.Size() > 0 {
+				t.Fatalf("runtime not unwound after %v for input %q", err, src)
+			}
+			return
+		}
+		// a program that finishes leaves at most the value of a return
+		// at the top level
+		if r.stack.Size() > 1 {
+			t.Fatalf("%d values left on the stack for input %q", r.stack.Size(), src)
+		}
+	})
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var update = flag.Bool("update", false, "update golden files")
+
+// goldenDirs are the corpora of programs whose behavior is checked
+// end-to-end. Each prog.kw is run with stdin read from prog.in, and its
+// stdout, stderr and exit status are compared against prog.out, prog.err and
+// prog.exit. Missing files stand for empty input and output and a zero exit
+// status. Run `go test -run TestGolden -update` to regenerate them after an
+// intended change in behavior.
+var goldenDirs = []string{"examples", "testdata/conformance"}
+
+// goldenTime is the time at which the golden programs start, on a clock
+// that only moves when they sleep.
+var goldenTime = time.Date(2024, time.March, 1, 9, 30, 0, 0, time.UTC)
+
+// goldenArgs are the command-line arguments given to the golden programs.
+var goldenArgs = []string{"-v", "input.txt"}
+
+// readGolden returns the contents of the named golden file, or an empty
+// string if it does not exist.
+func readGolden(t *testing.T, name string) string {
+	b, err := ioutil.ReadFile(name)
+	if os.IsNotExist(err) {
+		return ""
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(b)
+}
+
+// writeGolden replaces the named golden file, removing it when the content
+// is empty.
+func writeGolden(t *testing.T, name, content string) {
+	if content == "" {
+		if err := os.Remove(name); err != nil && !os.IsNotExist(err) {
+			t.Fatal(err)
+		}
+		return
+	}
+	if err := ioutil.WriteFile(name, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestGolden(t *testing.T) {
+	for _, dir := range goldenDirs {
+		files, err := filepath.Glob(filepath.Join(dir, "*.kw"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, file := range files {
+			file := file
+			t.Run(file, func(t *testing.T) {
+				base := strings.TrimSuffix(file, ".kw")
+				src, err := ioutil.ReadFile(file)
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				stdout := &bytes.Buffer{}
+				stderr := &bytes.Buffer{}
+				env := &RuntimeEnv{
+					stdin:  strings.NewReader(readGolden(t, base+".in")),
+					stdout: stdout,
+					stderr: stderr,
+					caps:   CapDefault,
+					clock:  &fakeClock{goldenTime},
+					args:   goldenArgs,
+				}
+				status := execute(bytes.NewReader(src), env)
+
+				exit := ""
+				if status != 0 {
+					exit = strconv.Itoa(status) + "\n"
+				}
+				if *update {
+					writeGolden(t, base+".out", stdout.String())
+					writeGolden(t, base+".err", stderr.String())
+					writeGolden(t, base+".exit", exit)
+					return
+				}
+				assert.Equal(t, readGolden(t, base+".out"), stdout.String(), "stdout")
+				assert.Equal(t, readGolden(t, base+".err"), stderr.String(), "stderr")
+				assert.Equal(t, readGolden(t, base+".exit"), exit, "exit status")
+			})
+		}
+	}
+}
@@ -0,0 +1,215 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strings"
+)
+
+// maxJSONDepth is the deepest that lists and maps may be nested when they
+// are encoded, as deep as the decoder allows.
+const maxJSONDepth = 10000
+
+// jsonEncoder writes values as JSON, one line per element of a container
+// when it has an indent, and on a single line otherwise.
+type jsonEncoder struct {
+	buf    strings.Builder
+	env    *RuntimeEnv
+	indent string
+	// strict makes values that JSON cannot represent an error, rather
+	// than writing them as they are cast to strings.
+	strict bool
+}
+
+// encodeJSON returns e encoded as JSON, indenting elements by indent.
+func encodeJSON(env *RuntimeEnv, e ScopeEntry, indent string) (string, error) {
+	enc := &jsonEncoder{env: env, indent: indent, strict: true}
+	if err := enc.encode(e, 0); err != nil {
+		return "", err
+	}
+	return enc.buf.String(), nil
+}
+
+func (enc *jsonEncoder) encode(e ScopeEntry, depth int) error {
+	if depth > maxJSONDepth {
+		return errors.New("value nested too deeply")
+	}
+	switch e.DataType {
+	case TypNull:
+		enc.buf.WriteString("null")
+	case TypBool, TypDecimal:
+		enc.buf.WriteString(castStr(nil, e))
+	case TypNumber:
+		if f, ok := e.Value.(float64); ok && (math.IsInf(f, 0) || math.IsNaN(f)) && enc.strict {
+			return fmt.Errorf("%s cannot be encoded as JSON", numToStr(f))
+		}
+		enc.buf.WriteString(numToStr(e.Value))
+	case TypString:
+		writeJSONString(&enc.buf, e.Value.(string))
+	case TypList:
+		list := e.Value.([]ScopeEntry)
+		enc.buf.WriteByte('[')
+		for i, v := range list {
+			enc.separate(i, depth+1)
+			if err := enc.encode(v, depth+1); err != nil {
+				return err
+			}
+		}
+		enc.close(len(list), depth, ']')
+	case TypMap:
+		m := e.Value.(*Map)
+		enc.buf.WriteByte('{')
+		for i, k := range m.Keys() {
+			enc.separate(i, depth+1)
+			writeJSONString(&enc.buf, k)
+			enc.buf.WriteByte(':')
+			if enc.indent != "" {
+				enc.buf.WriteByte(' ')
+			}
+			v, _ := m.Get(k)
+			if err := enc.encode(v, depth+1); err != nil {
+				return err
+			}
+		}
+		enc.close(m.Len(), depth, '}')
+	default:
+		if enc.strict {
+			return fmt.Errorf("%s cannot be encoded as JSON", dataTypeName(e.DataType))
+		}
+		enc.buf.WriteString(dataTypeName(e.DataType))
+	}
+	enc.env.checkSize(enc.buf.Len())
+	return nil
+}
+
+// separate begins the element i of a container, at the given depth.
+func (enc *jsonEncoder) separate(i, depth int) {
+	if i > 0 {
+		enc.buf.WriteByte(',')
+	}
+	enc.newline(depth)
+}
+
+// close ends a container holding n elements at the given depth with the
+// delimiter end.
+func (enc *jsonEncoder) close(n, depth int, end byte) {
+	if n > 0 {
+		enc.newline(depth)
+	}
+	enc.buf.WriteByte(end)
+}
+
+func (enc *jsonEncoder) newline(depth int) {
+	if enc.indent == "" {
+		return
+	}
+	enc.buf.WriteByte('\n')
+	for i := 0; i < depth; i++ {
+		enc.buf.WriteString(enc.indent)
+	}
+	enc.env.checkSize(enc.buf.Len())
+}
+
+// writeJSONString writes s to buf as a quoted JSON string. Bytes that are
+// not valid UTF-8 are replaced with U+FFFD.
+func writeJSONString(buf *strings.Builder, s string) {
+	buf.WriteByte('"')
+	for _, ch := range s {
+		switch {
+		case ch == '"' || ch == '\\':
+			buf.WriteByte('\\')
+			buf.WriteRune(ch)
+		case ch == '\n':
+			buf.WriteString(`\n`)
+		case ch == '\r':
+			buf.WriteString(`\r`)
+		case ch == '\t':
+			buf.WriteString(`\t`)
+		case ch < 0x20:
+			fmt.Fprintf(buf, `\u%04x`, ch)
+		default:
+			buf.WriteRune(ch)
+		}
+	}
+	buf.WriteByte('"')
+}
+
+// decodeJSON returns the value of the JSON document s. Objects become maps,
+// arrays lists, and numbers are integers when they are written as integers.
+// Strings that are not valid UTF-8 have U+FFFD in place of their invalid
+// bytes. An error gives the byte offset in s where decoding failed.
+func decodeJSON(env *RuntimeEnv, s string) (ScopeEntry, error) {
+	dec := json.NewDecoder(strings.NewReader(s))
+	dec.UseNumber()
+	e, err := decodeJSONValue(env, dec)
+	if err != nil {
+		var syntax *json.SyntaxError
+		switch {
+		case err == io.EOF || err == io.ErrUnexpectedEOF:
+			return e, fmt.Errorf("unexpected end of JSON input at offset %d", len(s))
+		case !errors.As(err, &syntax):
+			return e, fmt.Errorf("%v at offset %d", err, dec.InputOffset())
+		}
+		// the offset counts the bytes read, including any that is invalid
+		offset := syntax.Offset
+		if offset > 0 && !strings.HasPrefix(syntax.Error(), "unexpected end") {
+			offset--
+		}
+		return e, fmt.Errorf("%v at offset %d", syntax, offset)
+	}
+	end := int(dec.InputOffset())
+	if rest := strings.TrimLeft(s[end:], " \t\r\n"); rest != "" {
+		return e, fmt.Errorf("unexpected data after the value at offset %d",
+			len(s)-len(rest))
+	}
+	return e, nil
+}
+
+func decodeJSONValue(env *RuntimeEnv, dec *json.Decoder) (ScopeEntry, error) {
+	tok, err := dec.Token()
+	if err != nil {
+		return ScopeEntry{}, err
+	}
+	switch tok := tok.(type) {
+	case json.Delim:
+		if tok == '[' {
+			list := []ScopeEntry{}
+			for dec.More() {
+				e, err := decodeJSONValue(env, dec)
+				if err != nil {
+					return e, err
+				}
+				list = append(list, e)
+				env.checkSize(len(list))
+			}
+			_, err = dec.Token()
+			return ScopeEntry{TypList, list}, err
+		}
+		m := NewMap()
+		for dec.More() {
+			key, err := dec.Token()
+			if err != nil {
+				return ScopeEntry{}, err
+			}
+			e, err := decodeJSONValue(env, dec)
+			if err != nil {
+				return e, err
+			}
+			m.Set(key.(string), e)
+			env.checkSize(m.Len())
+		}
+		_, err = dec.Token()
+		return ScopeEntry{TypMap, m}, err
+	case json.Number:
+		v, _ := parseNum(string(tok))
+		return ScopeEntry{TypNumber, v}, nil
+	case string:
+		return ScopeEntry{TypString, tok}, nil
+	case bool:
+		return ScopeEntry{TypBool, tok}, nil
+	}
+	return ScopeEntry{TypNull, nil}, nil
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestJSON(t *testing.T) {
+	t.Parallel()
+
+	decode := func(s string) ScopeEntry {
+		e, err := decodeJSON(nil, s)
+		assert.Nil(t, err, s)
+		return e
+	}
+
+	t.Run("Decode values", func(t *testing.T) {
+		assert.Equal(t, ScopeEntry{TypNull, nil}, decode("null"))
+		assert.Equal(t, ScopeEntry{TypBool, true}, decode(" true "))
+		assert.Equal(t, ScopeEntry{TypNumber, int64(-42)}, decode("-42"))
+		assert.Equal(t, ScopeEntry{TypNumber, bigInt("18446744073709551616")}, decode("18446744073709551616"))
+		assert.Equal(t, ScopeEntry{TypNumber, 1500.0}, decode("1.5e3"))
+		assert.Equal(t, ScopeEntry{TypString, "é\n😀"}, decode(`"é\n😀"`))
+		assert.Equal(t, ScopeEntry{TypList, []ScopeEntry{}}, decode("[]"))
+
+		e := decode(`{"b": [1, "x", null], "a": {}, "b": 2}`)
+		m := e.Value.(*Map)
+		assert.Equal(t, []string{"b", "a"}, m.Keys())
+		v, _ := m.Get("b")
+		assert.Equal(t, ScopeEntry{TypNumber, int64(2)}, v)
+	})
+
+	t.Run("Report decode errors with offsets", func(t *testing.T) {
+		tests := []struct {
+			src, err string
+		}{
+			{`[1, 2`, "unexpected end of JSON input at offset 5"},
+			{``, "unexpected end of JSON input at offset 0"},
+			{`[1 2]`, "invalid character '2' after array element at offset 3"},
+			{`{"a" 1}`, "invalid character '1' after object key at offset 5"},
+			{`{1: 2}`, "object member name must be a string at offset 1"},
+			{`[tru]`, "invalid character ']' in literal true (expecting 'e') at offset 4"},
+			{`{"a": 1} x`, "unexpected data after the value at offset 9"},
+			{strings.Repeat("[", maxJSONDepth+1), "exceeded max depth at offset 10000"},
+		}
+		for _, test := range tests {
+			_, err := decodeJSON(nil, test.src)
+			assert.EqualError(t, err, test.err, test.src)
+		}
+	})
+
+	t.Run("Encode values", func(t *testing.T) {
+		m := NewMap()
+		m.Set("z", ScopeEntry{TypList, []ScopeEntry{
+			{TypNumber, int64(1)}, {TypNumber, 2.5}, {TypDecimal, dec("0.10")},
+		}})
+		m.Set("a", ScopeEntry{TypString, "say \"hi\"\n\x01<&>"})
+		m.Set("n", ScopeEntry{TypNull, nil})
+		m.Set("e", ScopeEntry{TypMap, NewMap()})
+		e := ScopeEntry{TypMap, m}
+
+		s, err := encodeJSON(nil, e, "")
+		assert.Nil(t, err)
+		assert.Equal(t, `{"z":[1,2.5,0.10],"a":"say \"hi\"\n\u0001<&>","n":null,"e":{}}`, s)
+
+		s, _ = encodeJSON(nil, e, "  ")
+		assert.Equal(t, "{\n"+
+			"  \"z\": [\n"+
+			"    1,\n"+
+			"    2.5,\n"+
+			"    0.10\n"+
+			"  ],\n"+
+			"  \"a\": \"say \\\"hi\\\"\\n\\u0001<&>\",\n"+
+			"  \"n\": null,\n"+
+			"  \"e\": {}\n"+
+			"}", s)
+	})
+
+	t.Run("Refuse values JSON cannot represent", func(t *testing.T) {
+		_, err := encodeJSON(nil, ScopeEntry{TypNumber, math.NaN()}, "")
+		assert.EqualError(t, err, "NaN cannot be encoded as JSON")
+		_, err = encodeJSON(nil, ScopeEntry{TypList, []ScopeEntry{{TypNumber, math.Inf(-1)}}}, "")
+		assert.EqualError(t, err, "-Inf cannot be encoded as JSON")
+	})
+
+	t.Run("Round trip", func(t *testing.T) {
+		src := `{"name":"kiwi","tags":["a","b"],"n":12345678901234567890,"f":0.1,"ok":false,"x":null}`
+		s, err := encodeJSON(nil, decode(src), "")
+		assert.Nil(t, err)
+		assert.Equal(t, src, s)
+		assert.True(t, valuesEqual(decode(src), decode(s)))
+	})
+
+	t.Run("Limit sizes", func(t *testing.T) {
+		env := testRuntimeEnv("")
+		env.maxSize = 3
+		assert.PanicsWithValue(t, ErrSizeLimit, func() {
+			decodeJSON(env, "[1, 2, 3, 4]")
+		})
+		assert.PanicsWithValue(t, ErrSizeLimit, func() {
+			encodeJSON(env, ScopeEntry{TypString, "long"}, "")
+		})
+	})
+}
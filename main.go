@@ -2,14 +2,239 @@ package main
 
 import (
 	"bufio"
+	"context"
+	"errors"
 	"fmt"
 	"io"
+	"io/ioutil"
 	"log"
+	"math/rand"
 	"os"
+	"strings"
 
 	"github.com/jawher/mow.cli"
 )
 
+// parseFile parses the program in the named file.
+func parseFile(name string) (*AstProgramNode, error) {
+	fp, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer fp.Close()
+	return NewParser(NewScanner(bufio.NewReader(fp))).Parse()
+}
+
+// stdEnv returns a runtime environment that reads from stdin and writes to
+// the process's standard output and error, granting the default
+// capabilities.
+func stdEnv(stdin io.Reader) *RuntimeEnv {
+	return &RuntimeEnv{
+		stdin:  stdin,
+		stdout: os.Stdout,
+		stderr: os.Stderr,
+		caps:   CapDefault,
+	}
+}
+
+// sandboxSpec is the spec of the options declared by sandboxOpts.
+const sandboxSpec = "[--allow-read...] [--allow-write...] [--allow-env] [-A]"
+
+// sandboxOpts declares the options of cmd that grant capabilities beyond
+// the defaults, and returns a function granting them to an environment.
+func sandboxOpts(cmd *cli.Cmd) func(*RuntimeEnv) *RuntimeEnv {
+	read := cmd.StringsOpt("allow-read", nil,
+		"allow reading files below the given directory")
+	write := cmd.StringsOpt("allow-write", nil,
+		"allow writing files below the given directory")
+	environ := cmd.BoolOpt("allow-env", false,
+		"allow access to environment variables")
+	all := cmd.BoolOpt("A allow-all", false, "allow everything")
+
+	return func(env *RuntimeEnv) *RuntimeEnv {
+		if *all {
+			env.caps = CapAll
+			return env
+		}
+		if len(*read) > 0 {
+			env.caps |= CapFSRead
+			env.readPaths = *read
+		}
+		if len(*write) > 0 {
+			env.caps |= CapFSWrite
+			env.writePaths = *write
+		}
+		if *environ {
+			env.caps |= CapEnv
+		}
+		return env
+	}
+}
+
+// seedOpt declares the --seed option of cmd, and returns a function that
+// seeds an environment's generator with it if it is given.
+func seedOpt(cmd *cli.Cmd) func(*RuntimeEnv) *RuntimeEnv {
+	var set bool
+	seed := cmd.Int(cli.IntOpt{
+		Name:      "seed",
+		Desc:      "seed the random number generator",
+		SetByUser: &set,
+	})
+
+	return func(env *RuntimeEnv) *RuntimeEnv {
+		if set {
+			env.rng = rand.New(rand.NewSource(int64(*seed)))
+		}
+		return env
+	}
+}
+
+// valueOpts are the options of kiwi and its run command that take their
+// value from the argument that follows them. An option declared with a
+// value must be added here, as TestValueOpts checks.
+var valueOpts = map[string]bool{
+	"--seed": true, "--allow-read": true, "--allow-write": true,
+	"--profile": true, "--profile-top": true, "--cover": true,
+	"--cover-format": true,
+}
+
+// endOptions returns the arguments of kiwi with "--" inserted after the
+// program's file, or the source given with -e, if any arguments follow it.
+// They are then given to the program even if they look like options. The
+// arguments of the test and debug commands are returned as they are.
+func endOptions(args []string) []string {
+	command := ""
+	for i := 0; i < len(args); i++ {
+		switch arg := args[i]; {
+		case arg == "--":
+			return args
+		case valueOpts[arg]:
+			i++
+			continue
+		case command == "" &&
+			(strings.HasPrefix(arg, "-e=") || strings.HasPrefix(arg, "--eval=")):
+			// the source given inline takes the place of the file
+		case strings.HasPrefix(arg, "-") && arg != "-":
+			continue
+		case command == "" && (arg == "test" || arg == "debug"):
+			return args
+		case command == "" && arg == "run":
+			command = arg
+			continue
+		}
+		if i+1 == len(args) {
+			return args
+		}
+		end := append(args[:i+1:i+1], "--")
+		return append(end, args[i+1:]...)
+	}
+	return args
+}
+
+// shebangOptions splits the first argument of kiwi into the options it
+// holds when they are separated by spaces. A "#!" line such as
+// "#!/usr/local/bin/kiwi --allow-env --seed 1" passes them to kiwi as a
+// single argument, ahead of the script's file. The arguments are returned
+// as they are unless they have that shape: the second is an existing file
+// and the first splits into nothing but options and their values, so that
+// an option such as -e='write("a b")' is not torn apart.
+func shebangOptions(args []string) []string {
+	if len(args) < 2 || !strings.HasPrefix(args[0], "-") ||
+		!strings.ContainsAny(args[0], " \t") {
+		return args
+	}
+	if info, err := os.Stat(args[1]); err != nil || info.IsDir() {
+		return args
+	}
+	opts := strings.Fields(args[0])
+	for i := 0; i < len(opts); i++ {
+		switch {
+		case valueOpts[opts[i]]:
+			i++
+		case !strings.HasPrefix(opts[i], "-"):
+			return args
+		}
+	}
+	return append(opts, args[1:]...)
+}
+
+// runProgram runs prog in a runtime observed by tracers, reporting any
+// runtime error on env's stderr. It returns the process exit status, which
+// is the program's own if it calls exit.
+func runProgram(prog *AstProgramNode, env *RuntimeEnv, tracers ...Tracer) int {
+	r := NewRuntime(env)
+	for _, t := range tracers {
+		r.AddTracer(t)
+	}
+	return exitStatus(env, r.Run(context.Background(), prog))
+}
+
+// exitStatus returns the process exit status for err, the outcome of a
+// program's run, reporting it on env's stderr unless it is nil or the
+// program called exit.
+func exitStatus(env *RuntimeEnv, err error) int {
+	var exit *ExitError
+	switch {
+	case err == nil:
+		return 0
+	case errors.As(err, &exit):
+		return exit.Code
+	}
+	fmt.Fprintln(env.stderr, err)
+	return 1
+}
+
+// runLines runs prog once for each line read from in, with the line, less
+// its line ending, bound to the variable line. Other variables keep their
+// values from one line to the next. If print is set, line is written to
+// env's stdout after each run, with any change the program made to it. It
+// returns the process exit status, stopping at the first error.
+func runLines(prog *AstProgramNode, env *RuntimeEnv, in io.Reader, print bool) int {
+	r := NewRuntime(env)
+	lines := bufio.NewReader(in)
+	for {
+		str, err := lines.ReadString('\n')
+		if str == "" && err != nil {
+			if err == io.EOF {
+				return 0
+			}
+			return exitStatus(env, err)
+		}
+		str = strings.TrimSuffix(strings.TrimSuffix(str, "\n"), "\r")
+		prog.Scope.SetVar("line", ScopeEntry{TypString, str})
+		if err := r.Run(context.Background(), prog); err != nil {
+			return exitStatus(env, err)
+		}
+		if print {
+			e, _ := prog.Scope.GetVar("line")
+			fmt.Fprintln(env.stdout, castStr(env, e))
+		}
+	}
+}
+
+// execute parses and runs the program read from src, reporting errors on
+// env's stderr. It returns the process exit status.
+func execute(src io.Reader, env *RuntimeEnv) int {
+	prog, err := NewParser(NewScanner(src)).Parse()
+	if err != nil {
+		fmt.Fprintln(env.stderr, err)
+		return 1
+	}
+	return runProgram(prog, env)
+}
+
+// executeLines parses the program read from src and runs it once for each
+// line read from in, as runLines does, reporting errors on env's stderr. It
+// returns the process exit status.
+func executeLines(src io.Reader, env *RuntimeEnv, in io.Reader, print bool) int {
+	prog, err := NewParser(NewScanner(src)).Parse()
+	if err != nil {
+		fmt.Fprintln(env.stderr, err)
+		return 1
+	}
+	return runLines(prog, env, in, print)
+}
+
 func main() {
 	defer func() {
 		if e := recover(); e != nil {
@@ -18,16 +243,32 @@ func main() {
 	}()
 
 	app := cli.App("kiwi", "the kiwi language interpreter")
-	app.Spec = "[-t] [FILE]"
+	app.Spec = "[-t] [--seed] " + sandboxSpec + " [-n | -p] [-e | FILE] [ARG...]"
 
 	tree := app.BoolOpt("t tree", false, "print out syntax tree")
+	var evalSet bool
+	eval := app.String(cli.StringOpt{
+		Name:      "e eval",
+		Desc:      "run the given source instead of a file",
+		SetByUser: &evalSet,
+	})
+	lines := app.BoolOpt("n lines", false,
+		"run the program once for each line of input, bound to line")
+	print := app.BoolOpt("p print", false,
+		"as -n, and write line after each run")
+	seed := seedOpt(app.Cmd)
+	sandbox := sandboxOpts(app.Cmd)
 	file := app.StringArg("FILE", "", "source file")
+	args := app.StringsArg("ARG", nil, "arguments given to the program")
 
 	app.Action = func() {
 		var fp io.Reader
-		if *file == "" {
+		switch {
+		case evalSet:
+			fp = strings.NewReader(*eval)
+		case *file == "":
 			fp = os.Stdin
-		} else {
+		default:
 			var err error
 			fp, err = os.Open(*file)
 			if err != nil {
@@ -35,6 +276,23 @@ func main() {
 			}
 		}
 
+		if !*tree && (*lines || *print) {
+			if !evalSet && *file == "" {
+				fmt.Fprintln(os.Stderr, "-n and -p need a program given with -e or FILE")
+				cli.Exit(1)
+			}
+			// the lines of the input are the program's to process, not
+			// to read
+			env := seed(sandbox(stdEnv(strings.NewReader(""))))
+			env.args = *args
+			cli.Exit(executeLines(bufio.NewReader(fp), env, os.Stdin, *print))
+		}
+		if !*tree {
+			env := seed(sandbox(stdEnv(os.Stdin)))
+			env.args = *args
+			cli.Exit(execute(bufio.NewReader(fp), env))
+		}
+
 		p := NewParser(NewScanner(bufio.NewReader(fp)))
 
 		n, err := p.Parse()
@@ -44,12 +302,204 @@ func main() {
 		if n == nil {
 			return
 		}
+		n.Accept(NewAstPrinter())
+	}
+
+	app.Command("run", "run a program", func(cmd *cli.Cmd) {
+		cmd.Spec = "[--profile] [--profile-top] [--cover] [--cover-format] " +
+			"[--seed] " + sandboxSpec + " FILE [ARG...]"
+
+		profile := cmd.StringOpt("profile", "",
+			"write a pprof execution profile to the given file")
+		top := cmd.IntOpt("profile-top", 10,
+			"number of functions and lines to list in the profile summary")
+		cover := cmd.StringOpt("cover", "",
+			"write a coverage report to the given file")
+		coverFormat := cmd.StringOpt("cover-format", "lcov",
+			"format of the coverage report, lcov or html")
+		file := cmd.StringArg("FILE", "", "source file")
+		args := cmd.StringsArg("ARG", nil, "arguments given to the program")
+		seed := seedOpt(cmd)
+		sandbox := sandboxOpts(cmd)
+
+		cmd.Action = func() {
+			if *coverFormat != "lcov" && *coverFormat != "html" {
+				fmt.Println("unknown coverage format " + *coverFormat)
+				cli.Exit(1)
+			}
+
+			src, err := ioutil.ReadFile(*file)
+			if err != nil {
+				panic(err)
+			}
+			n, err := NewParser(NewScanner(strings.NewReader(string(src)))).Parse()
+			if err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				cli.Exit(1)
+			}
+
+			var tracers []Tracer
+			var prof *Profiler
+			if *profile != "" {
+				prof = NewProfiler(*file)
+				tracers = append(tracers, prof)
+			}
+			var cov *Coverage
+			if *cover != "" {
+				cov = NewCoverage(*file, n)
+				tracers = append(tracers, cov)
+			}
+			env := seed(sandbox(stdEnv(os.Stdin)))
+			env.args = *args
+			status := runProgram(n, env, tracers...)
 
-		if *tree {
-			n.Accept(NewAstPrinter())
-		} else {
-			n.Accept(NewRuntime(&RuntimeEnv{os.Stdin, os.Stdout, os.Stderr}))
+			if prof != nil {
+				fp, err := os.Create(*profile)
+				if err != nil {
+					panic(err)
+				}
+				if err := prof.WritePprof(fp); err != nil {
+					panic(err)
+				}
+				fp.Close()
+				prof.WriteSummary(os.Stderr, *top)
+			}
+			if cov != nil {
+				fp, err := os.Create(*cover)
+				if err != nil {
+					panic(err)
+				}
+				if *coverFormat == "html" {
+					cov.WriteHTML(fp, strings.Split(string(src), "\n"))
+				} else {
+					cov.WriteLcov(fp)
+				}
+				fp.Close()
+				cov.WriteSummary(os.Stderr)
+			}
+			cli.Exit(status)
 		}
-	}
-	app.Run(os.Args)
+	})
+
+	app.Command("test", "run the test functions of *_test.kw files", func(cmd *cli.Cmd) {
+		cmd.Spec = "[--format] [--cover] [PATH...]"
+
+		format := cmd.StringOpt("format", "text",
+			"report format, text, tap or junit")
+		cover := cmd.StringOpt("cover", "",
+			"write an lcov coverage report to the given file")
+		paths := cmd.StringsArg("PATH", []string{"."},
+			"test files or directories to search")
+
+		cmd.Action = func() {
+			if *format != "text" && *format != "tap" && *format != "junit" {
+				fmt.Println("unknown report format " + *format)
+				cli.Exit(1)
+			}
+
+			files, err := FindTestFiles(*paths)
+			if err != nil {
+				panic(err)
+			}
+
+			var suites []*TestSuite
+			var covs []*Coverage
+			failed := false
+			for _, file := range files {
+				n, err := parseFile(file)
+				if err != nil {
+					suites = append(suites, &TestSuite{File: file, Err: err})
+					failed = true
+					continue
+				}
+				var tracers []Tracer
+				if *cover != "" {
+					cov := NewCoverage(file, n)
+					covs = append(covs, cov)
+					tracers = append(tracers, cov)
+				}
+				suite := RunTestSuite(file, n, tracers...)
+				suites = append(suites, suite)
+				failed = failed || suite.Failed()
+			}
+
+			switch *format {
+			case "tap":
+				WriteTestTAP(os.Stdout, suites)
+			case "junit":
+				if err := WriteTestJUnit(os.Stdout, suites); err != nil {
+					panic(err)
+				}
+			default:
+				WriteTestText(os.Stdout, suites)
+			}
+
+			if *cover != "" {
+				fp, err := os.Create(*cover)
+				if err != nil {
+					panic(err)
+				}
+				for _, cov := range covs {
+					cov.WriteLcov(fp)
+					cov.WriteSummary(os.Stderr)
+				}
+				fp.Close()
+			}
+			if failed {
+				cli.Exit(1)
+			}
+		}
+	})
+
+	app.Command("debug", "debug a program", func(cmd *cli.Cmd) {
+		cmd.Spec = "[--dap] [-i] [FILE]"
+
+		dap := cmd.BoolOpt("dap", false,
+			"serve the Debug Adapter Protocol on stdin and stdout")
+		input := cmd.StringOpt("i input", "",
+			"file to use as the program's standard input")
+		file := cmd.StringArg("FILE", "", "source file")
+
+		cmd.Action = func() {
+			if *dap {
+				if err := NewDapServer(os.Stdin, os.Stdout).Serve(); err != nil {
+					panic(err)
+				}
+				return
+			}
+			if *file == "" {
+				fmt.Println("a source file is required")
+				cli.Exit(1)
+			}
+
+			src, err := ioutil.ReadFile(*file)
+			if err != nil {
+				panic(err)
+			}
+			n, err := NewParser(NewScanner(strings.NewReader(string(src)))).Parse()
+			if err != nil {
+				fmt.Println(err)
+				return
+			}
+
+			var stdin io.Reader = strings.NewReader("")
+			if *input != "" {
+				fp, err := os.Open(*input)
+				if err != nil {
+					panic(err)
+				}
+				defer fp.Close()
+				stdin = fp
+			}
+
+			env := stdEnv(stdin)
+			console := NewDebugConsole(os.Stdin, os.Stdout,
+				strings.Split(string(src), "\n"))
+			err = NewDebugger(console, true).Run(context.Background(),
+				NewRuntime(env), n)
+			cli.Exit(exitStatus(env, err))
+		}
+	})
+
+	app.Run(append(os.Args[:1:1], endOptions(shebangOptions(os.Args[1:]))...))
 }
@@ -0,0 +1,183 @@
+package main
+
+import (
+	"bytes"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEndOptions(t *testing.T) {
+	t.Parallel()
+
+	for _, tc := range []struct {
+		args, expected string
+	}{
+		{"prog.kw", "prog.kw"},
+		{"prog.kw -v x", "prog.kw -- -v x"},
+		{"-t --seed 4 --allow-read=. prog.kw --seed 5", "-t --seed 4 --allow-read=. prog.kw -- --seed 5"},
+		{"--allow-write out prog.kw", "--allow-write out prog.kw"},
+		{"prog.kw -- -v", "prog.kw -- -- -v"},
+		{"-- prog.kw -v", "-- prog.kw -v"},
+		{"run --cover c.lcov prog.kw -A", "run --cover c.lcov prog.kw -- -A"},
+		{"test -v dir", "test -v dir"},
+		{"debug prog.kw -i in", "debug prog.kw -i in"},
+		{"-A", "-A"},
+		{"- x", "- -- x"},
+		{"-n -e write(line) -v", "-n -e write(line) -- -v"},
+		{"-p --eval=write(1) -v", "-p --eval=write(1) -- -v"},
+		{"-e=write(1)", "-e=write(1)"},
+	} {
+		assert.Equal(t, strings.Fields(tc.expected), endOptions(strings.Fields(tc.args)), tc.args)
+	}
+}
+
+func TestValueOpts(t *testing.T) {
+	t.Parallel()
+
+	file, err := parser.ParseFile(token.NewFileSet(), "main.go", nil, 0)
+	assert.Nil(t, err)
+
+	// collect the options declared in main.go that take a value, leaving
+	// out those of the test and debug commands, whose arguments endOptions
+	// returns as they are
+	var names []string
+	ast.Inspect(file, func(n ast.Node) bool {
+		var lit ast.Expr
+		switch n := n.(type) {
+		case *ast.CallExpr:
+			sel, ok := n.Fun.(*ast.SelectorExpr)
+			if !ok || len(n.Args) == 0 {
+				return true
+			}
+			if sel.Sel.Name == "Command" {
+				cmd, _ := strconv.Unquote(n.Args[0].(*ast.BasicLit).Value)
+				return cmd != "test" && cmd != "debug"
+			}
+			if strings.HasSuffix(sel.Sel.Name, "Opt") && sel.Sel.Name != "BoolOpt" {
+				lit = n.Args[0]
+			}
+		case *ast.CompositeLit:
+			sel, ok := n.Type.(*ast.SelectorExpr)
+			if !ok || !strings.HasSuffix(sel.Sel.Name, "Opt") || sel.Sel.Name == "BoolOpt" {
+				return true
+			}
+			for _, elt := range n.Elts {
+				if kv := elt.(*ast.KeyValueExpr); kv.Key.(*ast.Ident).Name == "Name" {
+					lit = kv.Value
+				}
+			}
+		}
+		if lit != nil {
+			name, _ := strconv.Unquote(lit.(*ast.BasicLit).Value)
+			names = append(names, strings.Fields(name)...)
+		}
+		return true
+	})
+
+	assert.Contains(t, names, "seed")
+	for _, name := range names {
+		opt := "--" + name
+		if len(name) == 1 {
+			opt = "-" + name
+		}
+		// the source given with -e takes the place of the file
+		if opt == "-e" || opt == "--eval" {
+			continue
+		}
+		assert.True(t, valueOpts[opt], "%s is missing from valueOpts", opt)
+	}
+}
+
+func TestShebangOptions(t *testing.T) {
+	t.Parallel()
+
+	dir, err := ioutil.TempDir("", "kiwi")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	tool := filepath.Join(dir, "tool.kw")
+	if err := ioutil.WriteFile(tool, []byte(""), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tc := range []struct {
+		args     []string
+		expected []string
+	}{
+		{[]string{"--allow-env --seed 1", tool, "-v"},
+			[]string{"--allow-env", "--seed", "1", tool, "-v"}},
+		{[]string{"--allow-env --seed 1", "missing.kw"},
+			[]string{"--allow-env --seed 1", "missing.kw"}},
+		{[]string{"-A", tool, "a b"}, []string{"-A", tool, "a b"}},
+		{[]string{"my tool.kw", "-v x"}, []string{"my tool.kw", "-v x"}},
+		{[]string{`-e=write("a b", "\n")`}, []string{`-e=write("a b", "\n")`}},
+		{[]string{`-e=write("a b")`, tool}, []string{`-e=write("a b")`, tool}},
+		{[]string{"--allow-read=/dir with space", "prog.kw"},
+			[]string{"--allow-read=/dir with space", "prog.kw"}},
+		{[]string{"--allow-write=/dir with space", tool},
+			[]string{"--allow-write=/dir with space", tool}},
+		{[]string{}, []string{}},
+	} {
+		assert.Equal(t, tc.expected, shebangOptions(tc.args))
+	}
+}
+
+func TestExecuteLines(t *testing.T) {
+	t.Parallel()
+
+	run := func(src, input string, print bool) (string, string, int) {
+		env := testRuntimeEnv("")
+		status := executeLines(strings.NewReader(src), env, strings.NewReader(input), print)
+		return env.stdout.(*bytes.Buffer).String(), env.stderr.(*bytes.Buffer).String(), status
+	}
+
+	t.Run("Run once per line", func(t *testing.T) {
+		out, _, status := run(`write("<", line, ">")`, "a\nb c\r\n\nlast", false)
+		assert.Equal(t, "<a><b c><><last>", out)
+		assert.Equal(t, 0, status)
+
+		out, _, _ = run(`write(line)`, "", false)
+		assert.Equal(t, "", out)
+	})
+
+	t.Run("Print line after each run", func(t *testing.T) {
+		out, _, status := run(`line := upper(line)`, "kiwi\nkea\n", true)
+		assert.Equal(t, "KIWI\nKEA\n", out)
+		assert.Equal(t, 0, status)
+
+		out, _, _ = run(`line := len(line):str`, "kiwi\n", true)
+		assert.Equal(t, "4\n", out)
+	})
+
+	t.Run("Keep variables between lines", func(t *testing.T) {
+		src := "if line = \"1\" { n := 0 }\nn := n + len(line)\nwrite(n, \" \")"
+		out, _, _ := run(src, "1\nab\ncde", false)
+		assert.Equal(t, "1 3 6 ", out)
+	})
+
+	t.Run("Stop at errors and exit", func(t *testing.T) {
+		out, errs, status := run(`write(line) x := y`, "a\nb", false)
+		assert.Equal(t, "a", out)
+		assert.Equal(t, "variable is not defined\n", errs)
+		assert.Equal(t, 1, status)
+
+		out, errs, status = run(`if line = "stop" { exit(3) } write(line)`, "a\nstop\nb", true)
+		assert.Equal(t, "aa\n", out)
+		assert.Equal(t, "", errs)
+		assert.Equal(t, 3, status)
+
+		_, errs, status = run(`write(`, "a", false)
+		assert.NotEqual(t, "", errs)
+		assert.Equal(t, 1, status)
+	})
+}
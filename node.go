@@ -5,6 +5,13 @@ type (
 		Accept(Visitor)
 	}
 
+	// AstStmtNode is a node that may appear as a statement and knows the
+	// source position at which it begins.
+	AstStmtNode interface {
+		AstNode
+		Position() Pos
+	}
+
 	AstAddNode struct {
 		Left  AstNode
 		Right AstNode
@@ -16,6 +23,7 @@ type (
 	}
 
 	AstAssignNode struct {
+		Pos
 		Name string
 		Expr AstNode
 	}
@@ -29,6 +37,10 @@ type (
 		Term AstNode
 	}
 
+	AstDecimalNode struct {
+		Value Decimal
+	}
+
 	AstDivideNode struct {
 		Left  AstNode
 		Right AstNode
@@ -40,12 +52,14 @@ type (
 	}
 
 	AstFuncCallNode struct {
+		Pos
 		Name string
 		Args []AstNode
 	}
 
 	AstFuncDefNode struct {
 		*Scope
+		Pos
 		Name string
 		Args []string
 		Body []AstNode
@@ -62,11 +76,25 @@ type (
 	}
 
 	AstIfNode struct {
+		Pos
 		Cond AstNode
 		Body []AstNode
 		Else []AstNode
 	}
 
+	// AstIndexNode is the character of the string Term at Index.
+	AstIndexNode struct {
+		Term  AstNode
+		Index AstNode
+	}
+
+	// AstInterpNode is an interpolated string. Strs holds the text
+	// around each of the embedded Exprs, so it has one more element.
+	AstInterpNode struct {
+		Strs  []string
+		Exprs []AstNode
+	}
+
 	AstLessEqualNode struct {
 		Left  AstNode
 		Right AstNode
@@ -101,7 +129,7 @@ type (
 	}
 
 	AstNumberNode struct {
-		Value float64
+		Value interface{} // int64, *big.Int or float64
 	}
 
 	AstOrNode struct {
@@ -113,15 +141,29 @@ type (
 		Term AstNode
 	}
 
+	AstPowerNode struct {
+		Left  AstNode
+		Right AstNode
+	}
+
 	AstProgramNode struct {
 		*Scope
 		Stmts []AstNode
 	}
 
 	AstReturnNode struct {
+		Pos
 		Expr AstNode
 	}
 
+	// AstSliceNode is the characters of the string Term from Low up to
+	// High. Low and High are nil when they are omitted.
+	AstSliceNode struct {
+		Term AstNode
+		Low  AstNode
+		High AstNode
+	}
+
 	AstStringNode struct {
 		Value string
 	}
@@ -136,6 +178,7 @@ type (
 	}
 
 	AstWhileNode struct {
+		Pos
 		Cond AstNode
 		Body []AstNode
 	}
@@ -161,6 +204,10 @@ func (n *AstCastNode) Accept(v Visitor) {
 	v.VisitCastNode(n)
 }
 
+func (n *AstDecimalNode) Accept(v Visitor) {
+	v.VisitDecimalNode(n)
+}
+
 func (n *AstDivideNode) Accept(v Visitor) {
 	v.VisitDivideNode(n)
 }
@@ -189,6 +236,14 @@ func (n *AstIfNode) Accept(v Visitor) {
 	v.VisitIfNode(n)
 }
 
+func (n *AstIndexNode) Accept(v Visitor) {
+	v.VisitIndexNode(n)
+}
+
+func (n *AstInterpNode) Accept(v Visitor) {
+	v.VisitInterpNode(n)
+}
+
 func (n *AstLessEqualNode) Accept(v Visitor) {
 	v.VisitLessEqualNode(n)
 }
@@ -229,6 +284,10 @@ func (n *AstPositiveNode) Accept(v Visitor) {
 	v.VisitPositiveNode(n)
 }
 
+func (n *AstPowerNode) Accept(v Visitor) {
+	v.VisitPowerNode(n)
+}
+
 func (n *AstProgramNode) Accept(v Visitor) {
 	v.VisitProgramNode(n)
 }
@@ -237,6 +296,10 @@ func (n *AstReturnNode) Accept(v Visitor) {
 	v.VisitReturnNode(n)
 }
 
+func (n *AstSliceNode) Accept(v Visitor) {
+	v.VisitSliceNode(n)
+}
+
 func (n *AstStringNode) Accept(v Visitor) {
 	v.VisitStringNode(n)
 }
@@ -0,0 +1,360 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+// Numbers are held as int64 values when they are whole and fit, promoted to
+// *big.Int when integer arithmetic overflows, and as float64 values once a
+// fractional value appears. Integer results that fit in an int64 are always
+// held as int64, so values of equal magnitude have a single representation.
+
+// parseNum converts the decimal string s to a number, reporting whether it
+// is valid.
+func parseNum(s string) (interface{}, bool) {
+	if i, err := strconv.ParseInt(s, 10, 64); err == nil {
+		return i, true
+	}
+	if b, ok := new(big.Int).SetString(s, 10); ok {
+		return b, true
+	}
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil && err.(*strconv.NumError).Err != strconv.ErrRange {
+		return int64(0), false
+	}
+	return f, true
+}
+
+// parseNumLit converts the numeric literal lit to a number. Integers may be
+// written in decimal, or in hexadecimal, octal or binary after a 0x, 0o or
+// 0b prefix. Decimal literals may also have a fraction or an exponent, which
+// makes them floats. Underscores may separate digits.
+func parseNumLit(lit string) (interface{}, error) {
+	base, kind := 10, ""
+	if len(lit) > 1 && lit[0] == '0' {
+		switch lit[1] {
+		case 'x', 'X':
+			base, kind = 16, "hexadecimal"
+		case 'o', 'O':
+			base, kind = 8, "octal"
+		case 'b', 'B':
+			base, kind = 2, "binary"
+		}
+	}
+	if base != 10 {
+		// an underscore may follow the prefix
+		digits := strings.TrimPrefix(lit[2:], "_")
+		if digits == "" {
+			return nil, fmt.Errorf("%s literal has no digits", kind)
+		}
+		if err := checkDigits(digits, base, kind); err != nil {
+			return nil, err
+		}
+		b, _ := new(big.Int).SetString(strings.Replace(digits, "_", "", -1), base)
+		return normInt(b), nil
+	}
+
+	mant, exp := lit, ""
+	if i := strings.IndexAny(lit, "eE"); i >= 0 {
+		mant, exp = lit[:i], lit[i+1:]
+	}
+	whole, frac := mant, ""
+	if i := strings.IndexByte(mant, '.'); i >= 0 {
+		whole, frac = mant[:i], mant[i+1:]
+		if strings.IndexByte(frac, '.') >= 0 {
+			return nil, errors.New("number literal has more than one decimal point")
+		}
+	}
+	for _, digits := range []string{whole, frac} {
+		if err := checkDigits(digits, 10, "decimal"); err != nil {
+			return nil, err
+		}
+	}
+	if mant != lit {
+		digits := strings.TrimLeft(exp, "+-")
+		if len(exp)-len(digits) > 1 || digits == "" {
+			return nil, errors.New("exponent has no digits")
+		}
+		if err := checkDigits(digits, 10, "decimal"); err != nil {
+			return nil, err
+		}
+	}
+
+	clean := strings.Replace(lit, "_", "", -1)
+	if mant == whole && mant == lit {
+		v, _ := parseNum(clean)
+		return v, nil
+	}
+	f, err := strconv.ParseFloat(clean, 64)
+	if err != nil {
+		return nil, errors.New("number literal out of range")
+	}
+	return f, nil
+}
+
+// checkDigits returns an error unless s consists of digits in base, with
+// single underscores allowed between them.
+func checkDigits(s string, base int, kind string) error {
+	for i, ch := range s {
+		if ch == '_' {
+			if i == 0 || i == len(s)-1 || s[i-1] == '_' {
+				return errors.New("'_' must separate successive digits")
+			}
+			continue
+		}
+		if digitVal(ch) >= base {
+			if base == 10 {
+				return fmt.Errorf("invalid character %q in number literal", ch)
+			}
+			return fmt.Errorf("invalid digit %q in %s literal", ch, kind)
+		}
+	}
+	return nil
+}
+
+// digitVal returns the value of the digit ch, or 16 if ch isn't a digit in
+// any of the bases numbers are written in.
+func digitVal(ch rune) int {
+	switch {
+	case ch >= '0' && ch <= '9':
+		return int(ch - '0')
+	case ch >= 'a' && ch <= 'f':
+		return int(ch - 'a' + 10)
+	case ch >= 'A' && ch <= 'F':
+		return int(ch - 'A' + 10)
+	}
+	return 16
+}
+
+// numToStr formats a number as a string. Floats are formatted with the
+// fewest digits that represent them exactly.
+func numToStr(v interface{}) string {
+	switch v := v.(type) {
+	case int64:
+		return strconv.FormatInt(v, 10)
+	case *big.Int:
+		return v.String()
+	}
+	return strconv.FormatFloat(v.(float64), 'f', -1, 64)
+}
+
+// isInt reports whether the number v is an integer value.
+func isInt(v interface{}) bool {
+	_, ok := v.(float64)
+	return !ok
+}
+
+// normInt returns b as an int64 if it fits.
+func normInt(b *big.Int) interface{} {
+	if b.IsInt64() {
+		return b.Int64()
+	}
+	return b
+}
+
+// toBig returns the integer number v as a *big.Int.
+func toBig(v interface{}) *big.Int {
+	if i, ok := v.(int64); ok {
+		return big.NewInt(i)
+	}
+	return v.(*big.Int)
+}
+
+// toFloat returns the number v as the nearest float64.
+func toFloat(v interface{}) float64 {
+	switch v := v.(type) {
+	case int64:
+		return float64(v)
+	case *big.Int:
+		f, _ := new(big.Float).SetInt(v).Float64()
+		return f
+	}
+	return v.(float64)
+}
+
+// numIsZero reports whether the number v is zero.
+func numIsZero(v interface{}) bool {
+	switch v := v.(type) {
+	case int64:
+		return v == 0
+	case *big.Int:
+		return v.Sign() == 0
+	}
+	return v.(float64) == 0
+}
+
+func numAdd(a, b interface{}) interface{} {
+	if x, ok := a.(int64); ok {
+		if y, ok := b.(int64); ok {
+			if s := x + y; (s > x) == (y > 0) {
+				return s
+			}
+		}
+	}
+	if isInt(a) && isInt(b) {
+		return normInt(new(big.Int).Add(toBig(a), toBig(b)))
+	}
+	return toFloat(a) + toFloat(b)
+}
+
+func numSub(a, b interface{}) interface{} {
+	if x, ok := a.(int64); ok {
+		if y, ok := b.(int64); ok {
+			if d := x - y; (d < x) == (y > 0) {
+				return d
+			}
+		}
+	}
+	if isInt(a) && isInt(b) {
+		return normInt(new(big.Int).Sub(toBig(a), toBig(b)))
+	}
+	return toFloat(a) - toFloat(b)
+}
+
+func numMul(a, b interface{}) interface{} {
+	if x, ok := a.(int64); ok {
+		if y, ok := b.(int64); ok {
+			if x == 0 || y == 0 {
+				return int64(0)
+			}
+			p := x * y
+			if p/y == x && !(x == -1 && y == math.MinInt64) &&
+				!(y == -1 && x == math.MinInt64) {
+				return p
+			}
+		}
+	}
+	if isInt(a) && isInt(b) {
+		return normInt(new(big.Int).Mul(toBig(a), toBig(b)))
+	}
+	return toFloat(a) * toFloat(b)
+}
+
+// numDiv divides a by b. Dividing integers gives an integer when the
+// division is exact, and otherwise the float nearest the exact quotient.
+// Division by zero follows floating point rules.
+func numDiv(a, b interface{}) interface{} {
+	if isInt(a) && isInt(b) && !numIsZero(b) {
+		x, y := toBig(a), toBig(b)
+		q, m := new(big.Int).QuoRem(x, y, new(big.Int))
+		if m.Sign() == 0 {
+			return normInt(q)
+		}
+		f, _ := new(big.Rat).SetFrac(x, y).Float64()
+		return f
+	}
+	return toFloat(a) / toFloat(b)
+}
+
+// maxPowBits is the size of the largest integer power computed exactly.
+// Larger powers are computed as floats.
+const maxPowBits = 1 << 20
+
+// numPow raises a to the power b. An integer raised to an integer is exact
+// when the power is whole and no larger than maxPowBits, and otherwise is
+// the nearest float.
+func numPow(a, b interface{}) interface{} {
+	if isInt(a) && isInt(b) {
+		x, y := toBig(a), toBig(b)
+		if y.Sign() < 0 {
+			return numDiv(int64(1), numPow(a, numNeg(b)))
+		}
+		if x.BitLen() <= 1 || y.IsInt64() && y.Int64() <= maxPowBits/int64(x.BitLen()) {
+			return normInt(new(big.Int).Exp(x, y, nil))
+		}
+	}
+	return math.Pow(toFloat(a), toFloat(b))
+}
+
+// numMod returns the remainder of dividing a by b, which has the sign of a.
+func numMod(a, b interface{}) interface{} {
+	if isInt(a) && isInt(b) && !numIsZero(b) {
+		if x, ok := a.(int64); ok {
+			if y, ok := b.(int64); ok {
+				return x % y
+			}
+		}
+		return normInt(new(big.Int).Rem(toBig(a), toBig(b)))
+	}
+	return math.Mod(toFloat(a), toFloat(b))
+}
+
+func numNeg(a interface{}) interface{} {
+	if x, ok := a.(int64); ok && x != math.MinInt64 {
+		return -x
+	}
+	if isInt(a) {
+		return normInt(new(big.Int).Neg(toBig(a)))
+	}
+	return -a.(float64)
+}
+
+func numAbs(a interface{}) interface{} {
+	if x, ok := a.(int64); ok && x >= 0 {
+		return x
+	}
+	if isInt(a) {
+		return normInt(new(big.Int).Abs(toBig(a)))
+	}
+	return math.Abs(a.(float64))
+}
+
+// numCmp compares the numbers a and b exactly, returning -1, 0 or +1. ok is
+// false if either is NaN, which is unordered.
+func numCmp(a, b interface{}) (c int, ok bool) {
+	if x, ok := a.(int64); ok {
+		if y, ok := b.(int64); ok {
+			switch {
+			case x < y:
+				return -1, true
+			case x > y:
+				return 1, true
+			}
+			return 0, true
+		}
+	}
+	if isInt(a) && isInt(b) {
+		return toBig(a).Cmp(toBig(b)), true
+	}
+	if math.IsNaN(toFloat(a)) || math.IsNaN(toFloat(b)) {
+		return 0, false
+	}
+	return toBigFloat(a).Cmp(toBigFloat(b)), true
+}
+
+// toBigFloat returns the number v, which is not NaN, as an exact *big.Float.
+func toBigFloat(v interface{}) *big.Float {
+	switch v := v.(type) {
+	case int64:
+		return new(big.Float).SetInt64(v)
+	case *big.Int:
+		return new(big.Float).SetInt(v)
+	}
+	return new(big.Float).SetFloat64(v.(float64))
+}
+
+// valuesEqual reports whether a and b have the same type and value. Numbers
+// are equal when their values are, however they are held, and containers
+// when their contents are.
+func valuesEqual(a, b ScopeEntry) bool {
+	if a.DataType != b.DataType {
+		return false
+	}
+	switch a.DataType {
+	case TypNumber:
+		c, ok := numCmp(a.Value, b.Value)
+		return ok && c == 0
+	case TypDecimal:
+		return a.Value.(Decimal).Cmp(b.Value.(Decimal)) == 0
+	case TypList:
+		return listsEqual(a.Value.([]ScopeEntry), b.Value.([]ScopeEntry))
+	case TypMap:
+		return mapsEqual(a.Value.(*Map), b.Value.(*Map))
+	}
+	return a.Value == b.Value
+}
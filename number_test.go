@@ -0,0 +1,167 @@
+package main
+
+import (
+	"math"
+	"math/big"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func bigInt(s string) *big.Int {
+	b, _ := new(big.Int).SetString(s, 10)
+	return b
+}
+
+func TestNumbers(t *testing.T) {
+	t.Parallel()
+
+	t.Run("Parse numbers", func(t *testing.T) {
+		v, ok := parseNum("42")
+		assert.True(t, ok)
+		assert.Equal(t, int64(42), v)
+
+		v, _ = parseNum("9223372036854775808")
+		assert.Equal(t, bigInt("9223372036854775808"), v)
+
+		v, _ = parseNum("4.2")
+		assert.Equal(t, 4.2, v)
+
+		v, ok = parseNum("foo")
+		assert.False(t, ok)
+		assert.Equal(t, int64(0), v)
+	})
+
+	t.Run("Parse literals", func(t *testing.T) {
+		tests := []struct {
+			lit      string
+			expected interface{}
+		}{
+			{"42", int64(42)},
+			{"1_000_000", int64(1000000)},
+			{"0xFF", int64(255)},
+			{"0X_ff_ff", int64(65535)},
+			{"0o755", int64(493)},
+			{"0b1010", int64(10)},
+			{"0x1_0000_0000_0000_0000", bigInt("18446744073709551616")},
+			{"99999999999999999999", bigInt("99999999999999999999")},
+			{".5", 0.5},
+			{"1.", 1.0},
+			{"3.141_592", 3.141592},
+			{"1e-9", 1e-9},
+			{"2.5E+3", 2500.0},
+			{"1e3", 1000.0},
+		}
+		for _, test := range tests {
+			v, err := parseNumLit(test.lit)
+			assert.Nil(t, err, test.lit)
+			assert.Equal(t, test.expected, v, test.lit)
+		}
+	})
+
+	t.Run("Report malformed literals", func(t *testing.T) {
+		tests := []struct {
+			lit, err string
+		}{
+			{"0x", "hexadecimal literal has no digits"},
+			{"0b_", "binary literal has no digits"},
+			{"0o78", "invalid digit '8' in octal literal"},
+			{"0b102", "invalid digit '2' in binary literal"},
+			{"0xFG", "invalid digit 'G' in hexadecimal literal"},
+			{"1e", "exponent has no digits"},
+			{"1e+", "exponent has no digits"},
+			{"1e+-2", "exponent has no digits"},
+			{"1_", "'_' must separate successive digits"},
+			{"1__0", "'_' must separate successive digits"},
+			{"1_.5", "'_' must separate successive digits"},
+			{"0x_1__0", "'_' must separate successive digits"},
+			{"12abc", "invalid character 'a' in number literal"},
+			{"1.2.3", "number literal has more than one decimal point"},
+			{"1e400", "number literal out of range"},
+		}
+		for _, test := range tests {
+			_, err := parseNumLit(test.lit)
+			assert.EqualError(t, err, test.err, test.lit)
+		}
+	})
+
+	t.Run("Format numbers", func(t *testing.T) {
+		assert.Equal(t, "-42", numToStr(int64(-42)))
+		assert.Equal(t, "9223372036854775808", numToStr(bigInt("9223372036854775808")))
+		assert.Equal(t, "0.1", numToStr(0.1))
+		assert.Equal(t, "100000000000000000000", numToStr(1e20))
+		assert.Equal(t, "+Inf", numToStr(math.Inf(1)))
+	})
+
+	t.Run("Promote on overflow", func(t *testing.T) {
+		assert.Equal(t, bigInt("9223372036854775808"), numAdd(int64(math.MaxInt64), int64(1)))
+		assert.Equal(t, bigInt("-9223372036854775809"), numSub(int64(math.MinInt64), int64(1)))
+		assert.Equal(t, bigInt("85070591730234615847396907784232501249"),
+			numMul(int64(math.MaxInt64), int64(math.MaxInt64)))
+		assert.Equal(t, bigInt("9223372036854775808"), numMul(int64(math.MinInt64), int64(-1)))
+		assert.Equal(t, bigInt("9223372036854775808"), numNeg(int64(math.MinInt64)))
+		assert.Equal(t, bigInt("9223372036854775808"), numAbs(int64(math.MinInt64)))
+	})
+
+	t.Run("Demote when small", func(t *testing.T) {
+		assert.Equal(t, int64(math.MaxInt64), numSub(bigInt("9223372036854775808"), int64(1)))
+		assert.Equal(t, int64(2), numDiv(bigInt("18446744073709551616"), bigInt("9223372036854775808")))
+	})
+
+	t.Run("Divide", func(t *testing.T) {
+		assert.Equal(t, int64(4), numDiv(int64(8), int64(2)))
+		assert.Equal(t, 3.5, numDiv(int64(7), int64(2)))
+		assert.Equal(t, math.Inf(1), numDiv(int64(1), int64(0)))
+		assert.Equal(t, 0.5, numDiv(bigInt("9223372036854775808"), bigInt("18446744073709551616")))
+	})
+
+	t.Run("Take remainders", func(t *testing.T) {
+		assert.Equal(t, int64(1), numMod(int64(7), int64(3)))
+		assert.Equal(t, int64(-1), numMod(int64(-7), int64(3)))
+		assert.Equal(t, int64(0), numMod(int64(math.MinInt64), int64(-1)))
+		assert.Equal(t, int64(6), numMod(bigInt("18446744073709551616"), int64(10)))
+		assert.Equal(t, 1.5, numMod(5.5, int64(2)))
+		assert.True(t, math.IsNaN(numMod(int64(1), int64(0)).(float64)))
+	})
+
+	t.Run("Raise to powers", func(t *testing.T) {
+		assert.Equal(t, int64(1024), numPow(int64(2), int64(10)))
+		assert.Equal(t, bigInt("18446744073709551616"), numPow(int64(2), int64(64)))
+		assert.Equal(t, int64(-1), numPow(int64(-1), bigInt("18446744073709551617")))
+		assert.Equal(t, 0.25, numPow(int64(2), int64(-2)))
+		assert.Equal(t, 3.0, numPow(int64(9), 0.5))
+		assert.Equal(t, math.Inf(1), numPow(int64(10), int64(1<<30)))
+		assert.Equal(t, math.Inf(1), numPow(int64(2), int64(1<<62)))
+		assert.Equal(t, math.Inf(1), numPow(int64(3), int64(math.MaxInt64)))
+	})
+
+	t.Run("Mix integers and floats", func(t *testing.T) {
+		assert.Equal(t, 3.5, numAdd(int64(1), 2.5))
+		assert.Equal(t, 1.8446744073709552e19, numAdd(bigInt("18446744073709551616"), 0.5))
+	})
+
+	t.Run("Compare exactly", func(t *testing.T) {
+		c, ok := numCmp(int64(1), 1.0)
+		assert.True(t, ok)
+		assert.Equal(t, 0, c)
+
+		c, _ = numCmp(bigInt("9007199254740993"), 9007199254740992.0)
+		assert.Equal(t, 1, c)
+
+		c, _ = numCmp(bigInt("-18446744073709551616"), int64(0))
+		assert.Equal(t, -1, c)
+
+		c, _ = numCmp(math.Inf(1), bigInt("18446744073709551616"))
+		assert.Equal(t, 1, c)
+
+		_, ok = numCmp(math.NaN(), int64(0))
+		assert.False(t, ok)
+	})
+
+	t.Run("Compare values", func(t *testing.T) {
+		assert.True(t, valuesEqual(ScopeEntry{TypNumber, int64(2)}, ScopeEntry{TypNumber, 2.0}))
+		assert.False(t, valuesEqual(ScopeEntry{TypNumber, math.NaN()}, ScopeEntry{TypNumber, math.NaN()}))
+		assert.False(t, valuesEqual(ScopeEntry{TypNumber, int64(1)}, ScopeEntry{TypBool, true}))
+		assert.True(t, valuesEqual(ScopeEntry{TypString, "a"}, ScopeEntry{TypString, "a"}))
+	})
+}
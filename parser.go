@@ -2,15 +2,17 @@ package main
 
 import (
 	"errors"
-	"strconv"
+	"fmt"
 	"strings"
 )
 
 type Parser struct {
 	curToken Token
 	curValue string
+	curPos   Pos
 	scanner  *Scanner
 	scope    *Scope
+	slicing  bool // a colon ends a slice bound rather than casting
 }
 
 func NewParser(s *Scanner) *Parser {
@@ -27,6 +29,7 @@ func NewParser(s *Scanner) *Parser {
 func (p *Parser) advance() {
 	for {
 		p.curToken, p.curValue = p.scanner.Scan()
+		p.curPos = p.scanner.Pos()
 		if p.curToken != TkComment {
 			return
 		}
@@ -53,6 +56,12 @@ func (p *Parser) consume(t Token) {
 	p.advance()
 }
 
+// literalError panics with err, an error in the literal lit written at pos.
+func (p *Parser) literalError(pos Pos, lit string, err error) {
+	panic(fmt.Sprintf("%d:%d: malformed literal %s: %v", pos.Line, pos.Col,
+		lit, err))
+}
+
 // Parse consumes the token stream and returns the parsed program as an AST
 // (ProgramNode). err is nil for a successful parse.
 func (p *Parser) Parse() (prog *AstProgramNode, err error) {
@@ -126,9 +135,9 @@ func (p *Parser) addExpr() AstNode {
 	return node
 }
 
-// mul-expr = cast-expr [mul-op mul-expr]
+// mul-expr = pow-expr [mul-op mul-expr]
 func (p *Parser) mulExpr() AstNode {
-	node := p.castExpr()
+	node := p.powExpr()
 	switch p.curToken {
 	case TkMultiply:
 		p.advance()
@@ -143,74 +152,183 @@ func (p *Parser) mulExpr() AstNode {
 	return node
 }
 
-// cast-expr = term [":" ident]
+// pow-expr = cast-expr ["**" pow-expr]
+func (p *Parser) powExpr() AstNode {
+	node := p.castExpr()
+	if p.curToken == TkPower {
+		p.advance()
+		return &AstPowerNode{Left: node, Right: p.powExpr()}
+	}
+	return node
+}
+
+// cast-expr = postfix-expr [":" ident]
 func (p *Parser) castExpr() AstNode {
-	node := p.term()
-	if p.curToken == TkColon {
+	node := p.postfixExpr()
+	if p.curToken == TkColon && !p.slicing {
 		p.advance()
 		return &AstCastNode{Cast: p.ident(), Term: node}
 	}
 	return node
 }
 
-// term = "(" expr ")" / ("+" / "-" / "~") term / boolean / number / string /
-//        func-call / ident
+// postfix-expr = term *("[" expr "]" / "[" [expr] ":" [expr] "]")
+func (p *Parser) postfixExpr() AstNode {
+	node := p.term()
+	for p.match(TkLBracket) {
+		p.advance()
+		var low, high AstNode
+		if !p.match(TkColon) {
+			low = p.boundExpr()
+			if p.match(TkRBracket) {
+				p.advance()
+				node = &AstIndexNode{Term: node, Index: low}
+				continue
+			}
+		}
+		p.consume(TkColon)
+		if !p.match(TkRBracket) {
+			high = p.boundExpr()
+		}
+		p.consume(TkRBracket)
+		node = &AstSliceNode{Term: node, Low: low, High: high}
+	}
+	return node
+}
+
+// boundExpr parses an index or slice bound, which a colon ends.
+func (p *Parser) boundExpr() AstNode {
+	slicing := p.slicing
+	p.slicing = true
+	defer func() { p.slicing = slicing }()
+	return p.expr()
+}
+
+// nestedExpr parses an expression nested within parentheses or a string,
+// where a colon casts even if the expression is within a slice bound.
+func (p *Parser) nestedExpr() AstNode {
+	slicing := p.slicing
+	p.slicing = false
+	defer func() { p.slicing = slicing }()
+	return p.expr()
+}
+
+// unary-operand = postfix-expr ["**" pow-expr]
+//
+// An exponent binds more tightly than the unary operator before its base,
+// so -2 ** 2 is -4.
+func (p *Parser) unaryOperand() AstNode {
+	node := p.postfixExpr()
+	if p.curToken == TkPower {
+		p.advance()
+		return &AstPowerNode{Left: node, Right: p.powExpr()}
+	}
+	return node
+}
+
+// term = "(" expr ")" / ("+" / "-" / "~") unary-operand / boolean / number /
+//        string / func-call / ident
 func (p *Parser) term() AstNode {
 	switch p.curToken {
 	case TkLParen:
 		p.advance()
-		node := p.expr()
+		node := p.nestedExpr()
 		p.consume(TkRParen)
 		return node
 	case TkAdd:
 		p.advance()
-		return &AstPositiveNode{p.term()}
+		return &AstPositiveNode{p.unaryOperand()}
 	case TkSubtract:
 		p.advance()
-		return &AstNegativeNode{p.term()}
+		return &AstNegativeNode{p.unaryOperand()}
 	case TkIf:
 		p.advance()
-		return &AstNotNode{p.term()}
+		return &AstNotNode{p.unaryOperand()}
 	case TkBool:
 		node := &AstBoolNode{strings.ToLower(p.curValue) == "true"}
 		p.advance()
 		return node
 	case TkNumber:
-		val, _ := strconv.ParseFloat(p.curValue, 64)
+		val, err := parseNumLit(p.curValue)
+		if err != nil {
+			p.literalError(p.curPos, p.curValue, err)
+		}
 		node := &AstNumberNode{val}
 		p.advance()
 		return node
+	case TkDecimal:
+		val, err := parseDecLit(p.curValue)
+		if err != nil {
+			p.literalError(p.curPos, p.curValue, err)
+		}
+		node := &AstDecimalNode{val}
+		p.advance()
+		return node
 	case TkString:
-		node := &AstStringNode{p.curValue}
+		val, err := parseStrLit(p.curValue)
+		if err != nil {
+			p.literalError(p.curPos, p.curValue, err)
+		}
+		node := &AstStringNode{val}
 		p.advance()
 		return node
+	case TkInterpStart:
+		return p.interp()
 	case TkIdentifier:
+		pos := p.curPos
 		name := p.ident()
 		if p.match(TkLParen) {
-			return &AstFuncCallNode{Name: name, Args: p.parenExprList()}
+			return &AstFuncCallNode{
+				Pos:  pos,
+				Name: name,
+				Args: p.parenExprList(),
+			}
 		}
 		return &AstVariableNode{Name: name}
 	}
 
-	panic("whoops?")
+	panic("unexpected lexeme " + p.curToken.String())
+}
+
+// interp = interp-start expr *(interp-mid expr) interp-end
+func (p *Parser) interp() *AstInterpNode {
+	pos := p.curPos
+	segs := []string{p.curValue}
+	node := &AstInterpNode{}
+	for !p.match(TkInterpEnd) {
+		p.advance()
+		node.Exprs = append(node.Exprs, p.nestedExpr())
+		if !p.match(TkInterpMid, TkInterpEnd) {
+			panic("unexpected lexeme " + p.curToken.String())
+		}
+		segs = append(segs, p.curValue)
+	}
+	strs, err := parseInterpLit(segs)
+	if err != nil {
+		// the expressions are elided from the literal
+		p.literalError(pos, strings.Join(segs, "…"), err)
+	}
+	node.Strs = strs
+	p.advance()
+	return node
 }
 
 // paren-expr-list = "(" [expr *("," expr)] ")"
 func (p *Parser) parenExprList() []AstNode {
-	defer p.consume(TkRParen)
 	p.consume(TkLParen)
 
 	var list []AstNode
-	if p.match(TkRParen) {
-		return list
-	}
-	for {
-		list = append(list, p.expr())
-		if !p.match(TkComma) {
-			return list
+	if !p.match(TkRParen) {
+		for {
+			list = append(list, p.nestedExpr())
+			if !p.match(TkComma) {
+				break
+			}
+			p.advance()
 		}
-		p.advance()
 	}
+	p.consume(TkRParen)
+	return list
 }
 
 // stmt = if-stmt / while-stmt / func-def / return-stmt / assign-stmt /
@@ -233,8 +351,9 @@ func (p *Parser) stmt() (node AstNode) {
 
 // if-stmt = "if" expr brace-stmt-list [else-clause]
 func (p *Parser) ifStmt() *AstIfNode {
+	pos := p.curPos
 	p.consume(TkIf)
-	node := &AstIfNode{Cond: p.expr(), Body: p.braceStmtList()}
+	node := &AstIfNode{Pos: pos, Cond: p.expr(), Body: p.braceStmtList()}
 	if p.match(TkElse) {
 		p.advance()
 		if p.match(TkLBrace) {
@@ -263,7 +382,7 @@ func (p *Parser) braceStmtList() (list []AstNode) {
 // Note: an else with an expression becomes an if-stmt within a default else
 // clause.
 func (p *Parser) elseClause() *AstIfNode {
-	node := &AstIfNode{Cond: p.expr(), Body: p.braceStmtList()}
+	node := &AstIfNode{Pos: p.curPos, Cond: p.expr(), Body: p.braceStmtList()}
 	if p.match(TkElse) {
 		p.advance()
 		if p.match(TkLBrace) {
@@ -277,15 +396,18 @@ func (p *Parser) elseClause() *AstIfNode {
 
 // while-stmt = "while" expr brace-stmt-list
 func (p *Parser) whileStmt() *AstWhileNode {
+	pos := p.curPos
 	p.consume(TkWhile)
-	return &AstWhileNode{Cond: p.expr(), Body: p.braceStmtList()}
+	return &AstWhileNode{Pos: pos, Cond: p.expr(), Body: p.braceStmtList()}
 }
 
 // func-def = "func" ident *ident brace-stmt-list
 func (p *Parser) funcDef() *AstFuncDefNode {
+	pos := p.curPos
 	p.consume(TkFunc)
 
 	node := &AstFuncDefNode{
+		Pos:  pos,
 		Name: p.ident(),
 	}
 	p.scope.SetFunc(node.Name, ScopeEntry{TypFunc, node})
@@ -307,10 +429,11 @@ func (p *Parser) funcDef() *AstFuncDefNode {
 
 // return-stmt = "return" [expr]
 func (p *Parser) returnStmt() *AstReturnNode {
+	pos := p.curPos
 	p.consume(TkReturn)
-	node := &AstReturnNode{}
-	if p.match(TkLParen, TkAdd, TkSubtract, TkIf, TkBool, TkNumber, TkString,
-		TkIdentifier) {
+	node := &AstReturnNode{Pos: pos}
+	if p.match(TkLParen, TkAdd, TkSubtract, TkIf, TkBool, TkNumber, TkDecimal, TkString,
+		TkInterpStart, TkIdentifier) {
 		node.Expr = p.expr()
 	}
 	return node
@@ -319,13 +442,14 @@ func (p *Parser) returnStmt() *AstReturnNode {
 // assign-stmt = ident ":=" expr
 // func-call   = ident paren-expr-list
 func (p *Parser) assignStmtOrFuncCall() AstNode {
+	pos := p.curPos
 	name := p.ident()
 	if p.match(TkAssign) {
 		p.advance()
-		return &AstAssignNode{Name: name, Expr: p.expr()}
+		return &AstAssignNode{Pos: pos, Name: name, Expr: p.expr()}
 	}
 	if p.match(TkLParen) {
-		return &AstFuncCallNode{Name: name, Args: p.parenExprList()}
+		return &AstFuncCallNode{Pos: pos, Name: name, Args: p.parenExprList()}
 	}
 	panic("unexpected " + p.curToken.String())
 }
@@ -48,13 +48,77 @@ func TestParser(t *testing.T) {
 	t.Run("Parse parenthesized term", func(t *testing.T) {
 		p := newParser("(42)")
 		node := p.term().(*AstNumberNode)
-		assert.Equal(t, 42.0, node.Value)
+		assert.Equal(t, int64(42), node.Value)
+	})
+
+	t.Run("Parse decimal term", func(t *testing.T) {
+		p := newParser("19.90d")
+		node := p.term().(*AstDecimalNode)
+		assert.Equal(t, "19.90", node.Value.String())
+	})
+
+	t.Run("Parse malformed number", func(t *testing.T) {
+		_, err := newParser("x := 1\ny := 0x").Parse()
+		assert.EqualError(t, err,
+			"2:6: malformed literal 0x: hexadecimal literal has no digits")
+
+		_, err = newParser("write(0x)").Parse()
+		assert.EqualError(t, err,
+			"1:7: malformed literal 0x: hexadecimal literal has no digits")
+	})
+
+	t.Run("Parse malformed string", func(t *testing.T) {
+		_, err := newParser(`x := "\q"`).Parse()
+		assert.EqualError(t, err,
+			`1:6: malformed literal "\q": unknown escape sequence \q`)
+
+		_, err = newParser(`write("\q")`).Parse()
+		assert.EqualError(t, err,
+			`1:7: malformed literal "\q": unknown escape sequence \q`)
+	})
+
+	t.Run("Parse interpolated string", func(t *testing.T) {
+		p := newParser(`"fib(${i}) = ${fib(i) + 1}\$"`)
+		node := p.term().(*AstInterpNode)
+		assert.Equal(t, []string{"fib(", ") = ", "$"}, node.Strs)
+		assert.Equal(t, "i", node.Exprs[0].(*AstVariableNode).Name)
+		assert.IsType(t, &AstAddNode{}, node.Exprs[1])
+	})
+
+	t.Run("Parse malformed interpolated string", func(t *testing.T) {
+		_, err := newParser(`x := "${1}\q"`).Parse()
+		assert.EqualError(t, err,
+			`1:6: malformed literal "${…}\q": unknown escape sequence \q`)
+
+		_, err = newParser(`x := "${1 2}"`).Parse()
+		assert.EqualError(t, err, "unexpected lexeme TkNumber")
 	})
 
 	t.Run("Parse signed term", func(t *testing.T) {
 		p := newParser("-42")
 		node := p.term().(*AstNegativeNode)
-		assert.Equal(t, 42.0, node.Term.(*AstNumberNode).Value)
+		assert.Equal(t, int64(42), node.Term.(*AstNumberNode).Value)
+	})
+
+	t.Run("Parse power", func(t *testing.T) {
+		p := newParser("2 ** 3 ** 2")
+		node := p.expr().(*AstPowerNode)
+		assert.Equal(t, int64(2), node.Left.(*AstNumberNode).Value)
+		assert.IsType(t, &AstPowerNode{}, node.Right)
+
+		p = newParser("2 * 3 ** 2")
+		mul := p.expr().(*AstMultiplyNode)
+		assert.IsType(t, &AstPowerNode{}, mul.Right)
+	})
+
+	t.Run("Parse signed power", func(t *testing.T) {
+		p := newParser("-2 ** 2")
+		node := p.expr().(*AstNegativeNode)
+		assert.IsType(t, &AstPowerNode{}, node.Term)
+
+		p = newParser("2 ** -1")
+		pow := p.expr().(*AstPowerNode)
+		assert.IsType(t, &AstNegativeNode{}, pow.Right)
 	})
 
 	t.Run("Parse cast", func(t *testing.T) {
@@ -64,6 +128,57 @@ func TestParser(t *testing.T) {
 		assert.Equal(t, "foo", node.Term.(*AstVariableNode).Name)
 	})
 
+	t.Run("Parse index", func(t *testing.T) {
+		p := newParser("s[i + 1]")
+		node := p.castExpr().(*AstIndexNode)
+		assert.Equal(t, "s", node.Term.(*AstVariableNode).Name)
+		assert.IsType(t, &AstAddNode{}, node.Index)
+	})
+
+	t.Run("Parse slices", func(t *testing.T) {
+		p := newParser("s[1:-1]")
+		node := p.castExpr().(*AstSliceNode)
+		assert.Equal(t, int64(1), node.Low.(*AstNumberNode).Value)
+		assert.IsType(t, &AstNegativeNode{}, node.High)
+
+		p = newParser("s[:j]")
+		node = p.castExpr().(*AstSliceNode)
+		assert.Nil(t, node.Low)
+		assert.Equal(t, "j", node.High.(*AstVariableNode).Name)
+
+		p = newParser("s[i:]")
+		node = p.castExpr().(*AstSliceNode)
+		assert.Equal(t, "i", node.Low.(*AstVariableNode).Name)
+		assert.Nil(t, node.High)
+
+		p = newParser("s[:]")
+		node = p.castExpr().(*AstSliceNode)
+		assert.Nil(t, node.Low)
+		assert.Nil(t, node.High)
+	})
+
+	t.Run("Parse casts around indexes", func(t *testing.T) {
+		// a colon within the brackets is a cast only within parentheses
+		p := newParser("s[(n:num):f(x:num)][0]:num")
+		cast := p.castExpr().(*AstCastNode)
+		assert.Equal(t, "num", cast.Cast)
+		index := cast.Term.(*AstIndexNode)
+		slice := index.Term.(*AstSliceNode)
+		assert.Equal(t, "num", slice.Low.(*AstCastNode).Cast)
+		assert.Equal(t, "num", slice.High.(*AstFuncCallNode).Args[0].(*AstCastNode).Cast)
+	})
+
+	t.Run("Parse negated index", func(t *testing.T) {
+		p := newParser("-s[0]")
+		node := p.castExpr().(*AstNegativeNode)
+		assert.IsType(t, &AstIndexNode{}, node.Term)
+	})
+
+	t.Run("Parse unclosed index", func(t *testing.T) {
+		_, err := newParser("x := s[1").Parse()
+		assert.EqualError(t, err, "unexpected lexeme TkEOF")
+	})
+
 	t.Run("Parse func call term", func(t *testing.T) {
 		p := newParser("foo()")
 		node := p.term().(*AstFuncCallNode)
@@ -76,7 +191,7 @@ func TestParser(t *testing.T) {
 		node := p.term().(*AstFuncCallNode)
 		assert.Equal(t, "foo", node.Name)
 		assert.Equal(t, "bar", node.Args[0].(*AstVariableNode).Name)
-		assert.Equal(t, 42.0, node.Args[1].(*AstNumberNode).Value)
+		assert.Equal(t, int64(42), node.Args[1].(*AstNumberNode).Value)
 		assert.Equal(t, "baz", node.Args[2].(*AstStringNode).Value)
 	})
 
@@ -90,9 +205,9 @@ func TestParser(t *testing.T) {
 		p := newParser("{foo := 42 bar := 73}")
 		node := p.braceStmtList()
 		assert.Equal(t, "foo", node[0].(*AstAssignNode).Name)
-		assert.Equal(t, 42.0, node[0].(*AstAssignNode).Expr.(*AstNumberNode).Value)
+		assert.Equal(t, int64(42), node[0].(*AstAssignNode).Expr.(*AstNumberNode).Value)
 		assert.Equal(t, "bar", node[1].(*AstAssignNode).Name)
-		assert.Equal(t, 73.0, node[1].(*AstAssignNode).Expr.(*AstNumberNode).Value)
+		assert.Equal(t, int64(73), node[1].(*AstAssignNode).Expr.(*AstNumberNode).Value)
 	})
 
 	t.Run("Parse braced statement list with statement error", func(t *testing.T) {
@@ -163,7 +278,7 @@ func TestParser(t *testing.T) {
 	t.Run("Parse return statement", func(t *testing.T) {
 		p := newParser("return 42\n")
 		node := p.stmt().(*AstReturnNode)
-		assert.Equal(t, 42.0, node.Expr.(*AstNumberNode).Value)
+		assert.Equal(t, int64(42), node.Expr.(*AstNumberNode).Value)
 	})
 
 	t.Run("Parse return statement without expression", func(t *testing.T) {
@@ -198,8 +313,8 @@ func TestParser(t *testing.T) {
 		p := newParser("foo := 42 + 73\n")
 		node := p.stmt().(*AstAssignNode)
 		assert.Equal(t, "foo", node.Name)
-		assert.Equal(t, 42.0, node.Expr.(*AstAddNode).Left.(*AstNumberNode).Value)
-		assert.Equal(t, 73.0, node.Expr.(*AstAddNode).Right.(*AstNumberNode).Value)
+		assert.Equal(t, int64(42), node.Expr.(*AstAddNode).Left.(*AstNumberNode).Value)
+		assert.Equal(t, int64(73), node.Expr.(*AstAddNode).Right.(*AstNumberNode).Value)
 	})
 
 	t.Run("Parse assignment statement with expression error", func(t *testing.T) {
@@ -215,4 +330,14 @@ func TestParser(t *testing.T) {
 		assert.Equal(t, "foo", node.Name)
 		assert.Equal(t, 0, len(node.Args))
 	})
+
+	t.Run("Parse statement positions", func(t *testing.T) {
+		p := newParser("foo := 42\nif true {\n  bar()\n}")
+		prog, err := p.Parse()
+		assert.Nil(t, err)
+		assert.Equal(t, Pos{1, 1}, prog.Stmts[0].(AstStmtNode).Position())
+		node := prog.Stmts[1].(*AstIfNode)
+		assert.Equal(t, Pos{2, 1}, node.Position())
+		assert.Equal(t, Pos{3, 3}, node.Body[0].(AstStmtNode).Position())
+	})
 }
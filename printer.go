@@ -78,6 +78,11 @@ func (p AstPrinter) VisitCastNode(n *AstCastNode) {
 	p.pop()
 }
 
+func (p AstPrinter) VisitDecimalNode(n *AstDecimalNode) {
+	fmt.Println("DecimalNode")
+	fmt.Println(p.peek() + "╰ Value: " + n.Value.String())
+}
+
 func (p AstPrinter) VisitDivideNode(n *AstDivideNode) {
 	fmt.Println("DivideNode")
 	fmt.Print(p.peek() + "├ Left: ")
@@ -207,6 +212,35 @@ func (p AstPrinter) VisitIfNode(n *AstIfNode) {
 	}
 }
 
+func (p AstPrinter) VisitIndexNode(n *AstIndexNode) {
+	fmt.Println("IndexNode")
+	fmt.Print(p.peek() + "├ Term: ")
+	p.push(p.peek() + "│       ")
+	n.Term.Accept(p)
+	p.pop()
+	fmt.Print(p.peek() + "╰ Index: ")
+	p.push(p.peek() + "         ")
+	n.Index.Accept(p)
+	p.pop()
+}
+
+func (p AstPrinter) VisitInterpNode(n *AstInterpNode) {
+	fmt.Println("InterpNode")
+	strs := make([]string, len(n.Strs))
+	for i, str := range n.Strs {
+		strs[i] = quoteStr(str)
+	}
+	fmt.Println(p.peek() + "├ Strs: " + strings.Join(strs, ", "))
+	fmt.Print(p.peek() + "╰ Exprs: ")
+	p.push(p.peek() + "         ")
+	n.Exprs[0].Accept(p)
+	for _, expr := range n.Exprs[1:] {
+		fmt.Print(p.peek())
+		expr.Accept(p)
+	}
+	p.pop()
+}
+
 func (p AstPrinter) VisitLessEqualNode(n *AstLessEqualNode) {
 	fmt.Println("LessEqualNode")
 	fmt.Print(p.peek() + "├ Left: ")
@@ -285,12 +319,7 @@ func (p AstPrinter) VisitNotNode(n *AstNotNode) {
 
 func (p AstPrinter) VisitNumberNode(n *AstNumberNode) {
 	fmt.Println("NumberNode")
-	// numbers are presented as integers if they are whole, as
-	// floats if they have a decimal
-	value := fmt.Sprintf("%f", n.Value)
-	value = strings.TrimRight(value, "0")
-	value = strings.TrimRight(value, ".")
-	fmt.Println(p.peek() + "╰ Value: " + value)
+	fmt.Println(p.peek() + "╰ Value: " + numToStr(n.Value))
 }
 
 func (p AstPrinter) VisitOrNode(n *AstOrNode) {
@@ -313,6 +342,18 @@ func (p AstPrinter) VisitPositiveNode(n *AstPositiveNode) {
 	p.pop()
 }
 
+func (p AstPrinter) VisitPowerNode(n *AstPowerNode) {
+	fmt.Println("PowerNode")
+	fmt.Print(p.peek() + "├ Left: ")
+	p.push(p.peek() + "│       ")
+	n.Left.Accept(p)
+	p.pop()
+	fmt.Print(p.peek() + "╰ Right: ")
+	p.push(p.peek() + "         ")
+	n.Right.Accept(p)
+	p.pop()
+}
+
 func (p AstPrinter) VisitProgramNode(n *AstProgramNode) {
 	fmt.Println("ProgramNode")
 	fmt.Print(p.peek() + "╰ Stmts: ")
@@ -339,10 +380,37 @@ func (p AstPrinter) VisitReturnNode(n *AstReturnNode) {
 	p.pop()
 }
 
+func (p AstPrinter) VisitSliceNode(n *AstSliceNode) {
+	fmt.Println("SliceNode")
+	fmt.Print(p.peek() + "├ Term: ")
+	p.push(p.peek() + "│       ")
+	n.Term.Accept(p)
+	p.pop()
+	fmt.Print(p.peek() + "├ Low: ")
+	if n.Low == nil {
+		fmt.Println("0x0")
+	} else {
+		p.push(p.peek() + "│      ")
+		n.Low.Accept(p)
+		p.pop()
+	}
+	fmt.Print(p.peek() + "╰ High: ")
+	if n.High == nil {
+		fmt.Println("0x0")
+	} else {
+		p.push(p.peek() + "        ")
+		n.High.Accept(p)
+		p.pop()
+	}
+}
+
 func (p AstPrinter) VisitStringNode(n *AstStringNode) {
 	fmt.Println("StringNode")
-	// strings are presented in quotes with its special characters
-	// escaped
+	fmt.Println(p.peek() + "╰ Value: " + quoteStr(n.Value))
+}
+
+// quoteStr presents a string in quotes with its special characters escaped.
+func quoteStr(s string) string {
 	r := strings.NewReplacer(
 		"\\\\", "\\",
 		"\r", "\\r",
@@ -350,8 +418,7 @@ func (p AstPrinter) VisitStringNode(n *AstStringNode) {
 		"\t", "\\t",
 		"\"", "\\\"",
 	)
-	value := "\"" + r.Replace(n.Value) + "\""
-	fmt.Println(p.peek() + "╰ Value: " + value)
+	return "\"" + r.Replace(s) + "\""
 }
 
 func (p AstPrinter) VisitSubtractNode(n *AstSubtractNode) {
@@ -51,6 +51,39 @@ func TestPrintNumberNode(t *testing.T) {
 	assert.Equal(t, expected, actual)
 }
 
+func TestPrintIndexNode(t *testing.T) {
+	expected := "IndexNode\n" +
+		"├ Term: VariableNode\n" +
+		"│       ╰ Name: foo\n" +
+		"╰ Index: NumberNode\n" +
+		"         ╰ Value: 1\n"
+	actual := capture(func() {
+		n := &AstIndexNode{
+			Term:  &AstVariableNode{Name: "foo"},
+			Index: &AstNumberNode{int64(1)},
+		}
+		n.Accept(NewAstPrinter())
+	})
+	assert.Equal(t, expected, actual)
+}
+
+func TestPrintSliceNode(t *testing.T) {
+	expected := "SliceNode\n" +
+		"├ Term: VariableNode\n" +
+		"│       ╰ Name: foo\n" +
+		"├ Low: 0x0\n" +
+		"╰ High: NumberNode\n" +
+		"        ╰ Value: -1\n"
+	actual := capture(func() {
+		n := &AstSliceNode{
+			Term: &AstVariableNode{Name: "foo"},
+			High: &AstNumberNode{int64(-1)},
+		}
+		n.Accept(NewAstPrinter())
+	})
+	assert.Equal(t, expected, actual)
+}
+
 func TestPrintStringNode(t *testing.T) {
 	expected := "StringNode\n" +
 		"╰ Value: \"foo\"\n"
@@ -79,8 +112,8 @@ func TestPrintAddNode(t *testing.T) {
 		"         ╰ Value: 73\n"
 	actual := capture(func() {
 		n := &AstAddNode{
-			Left:  &AstNumberNode{42},
-			Right: &AstNumberNode{73},
+			Left:  &AstNumberNode{int64(42)},
+			Right: &AstNumberNode{int64(73)},
 		}
 		n.Accept(NewAstPrinter())
 	})
@@ -141,8 +174,8 @@ func TestPrintDivideNode(t *testing.T) {
 		"         ╰ Value: 21\n"
 	actual := capture(func() {
 		n := &AstDivideNode{
-			Left:  &AstNumberNode{42},
-			Right: &AstNumberNode{21},
+			Left:  &AstNumberNode{int64(42)},
+			Right: &AstNumberNode{int64(21)},
 		}
 		n.Accept(NewAstPrinter())
 	})
@@ -177,7 +210,7 @@ func TestPrintFuncCallNode(t *testing.T) {
 			Name: "foo",
 			Args: []AstNode{
 				&AstBoolNode{true},
-				&AstNumberNode{42},
+				&AstNumberNode{int64(42)},
 			},
 		}
 		n.Accept(NewAstPrinter())
@@ -204,8 +237,8 @@ func TestPrintGreaterEqualNode(t *testing.T) {
 		"         ╰ Value: 1776\n"
 	actual := capture(func() {
 		n := &AstGreaterEqualNode{
-			Left:  &AstNumberNode{1984},
-			Right: &AstNumberNode{1776},
+			Left:  &AstNumberNode{int64(1984)},
+			Right: &AstNumberNode{int64(1776)},
 		}
 		n.Accept(NewAstPrinter())
 	})
@@ -220,8 +253,8 @@ func TestPrintGreaterNode(t *testing.T) {
 		"         ╰ Value: 1776\n"
 	actual := capture(func() {
 		n := &AstGreaterNode{
-			Left:  &AstNumberNode{1984},
-			Right: &AstNumberNode{1776},
+			Left:  &AstNumberNode{int64(1984)},
+			Right: &AstNumberNode{int64(1776)},
 		}
 		n.Accept(NewAstPrinter())
 	})
@@ -246,7 +279,7 @@ func TestPrintIfNode(t *testing.T) {
 			Body: []AstNode{
 				&AstAssignNode{
 					Name: "foo",
-					Expr: &AstNumberNode{42},
+					Expr: &AstNumberNode{int64(42)},
 				},
 			},
 			Else: []AstNode{
@@ -274,6 +307,26 @@ func TestPrintIfNodeNoBodies(t *testing.T) {
 	assert.Equal(t, expected, actual)
 }
 
+func TestPrintInterpNode(t *testing.T) {
+	expected := "InterpNode\n" +
+		"├ Strs: \"a\", \"\\n\", \"\"\n" +
+		"╰ Exprs: VariableNode\n" +
+		"         ╰ Name: foo\n" +
+		"         NumberNode\n" +
+		"         ╰ Value: 42\n"
+	actual := capture(func() {
+		n := &AstInterpNode{
+			Strs: []string{"a", "\n", ""},
+			Exprs: []AstNode{
+				&AstVariableNode{Name: "foo"},
+				&AstNumberNode{int64(42)},
+			},
+		}
+		n.Accept(NewAstPrinter())
+	})
+	assert.Equal(t, expected, actual)
+}
+
 func TestPrintLessEqualNode(t *testing.T) {
 	expected := "LessEqualNode\n" +
 		"├ Left: NumberNode\n" +
@@ -282,8 +335,8 @@ func TestPrintLessEqualNode(t *testing.T) {
 		"         ╰ Value: 1984\n"
 	actual := capture(func() {
 		n := &AstLessEqualNode{
-			Left:  &AstNumberNode{1776},
-			Right: &AstNumberNode{1984},
+			Left:  &AstNumberNode{int64(1776)},
+			Right: &AstNumberNode{int64(1984)},
 		}
 		n.Accept(NewAstPrinter())
 	})
@@ -298,8 +351,8 @@ func TestPrintLessNode(t *testing.T) {
 		"         ╰ Value: 1984\n"
 	actual := capture(func() {
 		n := &AstLessNode{
-			Left:  &AstNumberNode{1776},
-			Right: &AstNumberNode{1984},
+			Left:  &AstNumberNode{int64(1776)},
+			Right: &AstNumberNode{int64(1984)},
 		}
 		n.Accept(NewAstPrinter())
 	})
@@ -314,8 +367,8 @@ func TestPrintModuloNode(t *testing.T) {
 		"         ╰ Value: 7\n"
 	actual := capture(func() {
 		n := &AstModuloNode{
-			Left:  &AstNumberNode{11},
-			Right: &AstNumberNode{7},
+			Left:  &AstNumberNode{int64(11)},
+			Right: &AstNumberNode{int64(7)},
 		}
 		n.Accept(NewAstPrinter())
 	})
@@ -330,8 +383,8 @@ func TestPrintMultiplyNode(t *testing.T) {
 		"         ╰ Value: 2\n"
 	actual := capture(func() {
 		n := &AstMultiplyNode{
-			Left:  &AstNumberNode{21},
-			Right: &AstNumberNode{2},
+			Left:  &AstNumberNode{int64(21)},
+			Right: &AstNumberNode{int64(2)},
 		}
 		n.Accept(NewAstPrinter())
 	})
@@ -343,7 +396,7 @@ func TestPrintNegativeNode(t *testing.T) {
 		"╰ Term: NumberNode\n" +
 		"        ╰ Value: 42\n"
 	actual := capture(func() {
-		n := &AstNegativeNode{&AstNumberNode{42}}
+		n := &AstNegativeNode{&AstNumberNode{int64(42)}}
 		n.Accept(NewAstPrinter())
 	})
 	assert.Equal(t, expected, actual)
@@ -397,7 +450,23 @@ func TestPrintPositiveNode(t *testing.T) {
 		"╰ Term: NumberNode\n" +
 		"        ╰ Value: 42\n"
 	actual := capture(func() {
-		n := &AstPositiveNode{&AstNumberNode{42}}
+		n := &AstPositiveNode{&AstNumberNode{int64(42)}}
+		n.Accept(NewAstPrinter())
+	})
+	assert.Equal(t, expected, actual)
+}
+
+func TestPrintPowerNode(t *testing.T) {
+	expected := "PowerNode\n" +
+		"├ Left: NumberNode\n" +
+		"│       ╰ Value: 2\n" +
+		"╰ Right: NumberNode\n" +
+		"         ╰ Value: 10\n"
+	actual := capture(func() {
+		n := &AstPowerNode{
+			Left:  &AstNumberNode{int64(2)},
+			Right: &AstNumberNode{int64(10)},
+		}
 		n.Accept(NewAstPrinter())
 	})
 	assert.Equal(t, expected, actual)
@@ -460,8 +529,8 @@ func TestPrintSubtractNode(t *testing.T) {
 		"         ╰ Value: 42\n"
 	actual := capture(func() {
 		n := &AstSubtractNode{
-			Left:  &AstNumberNode{73},
-			Right: &AstNumberNode{42},
+			Left:  &AstNumberNode{int64(73)},
+			Right: &AstNumberNode{int64(42)},
 		}
 		n.Accept(NewAstPrinter())
 	})
@@ -486,7 +555,7 @@ func TestPrintWhileNode(t *testing.T) {
 			Body: []AstNode{
 				&AstAssignNode{
 					Name: "foo",
-					Expr: &AstNumberNode{42},
+					Expr: &AstNumberNode{int64(42)},
 				},
 				&AstAssignNode{
 					Name: "bar",
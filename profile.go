@@ -0,0 +1,327 @@
+package main
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+)
+
+type (
+	// profLoc identifies a source line within a function.
+	profLoc struct {
+		fn   string
+		line int
+	}
+
+	// profSample accumulates the cost of a distinct call stack, leaf first.
+	profSample struct {
+		stack []profLoc
+		count int64
+		nanos int64
+	}
+
+	// profFunc accumulates the cost of a function.
+	profFunc struct {
+		name  string
+		calls int64
+		flat  int64
+		cum   int64
+	}
+
+	// profLine accumulates the cost of a source line.
+	profLine struct {
+		loc   profLoc
+		count int64
+		nanos int64
+	}
+)
+
+// Profiler implements the Tracer interface to measure how many statements
+// execute and how much time is spent in each function and source line.
+// Time between two events is charged to the call stack that was current
+// when the first of them occurred.
+type Profiler struct {
+	file    string
+	now     func() time.Time
+	start   time.Time
+	last    time.Time
+	stack   []profLoc
+	key     string
+	samples map[string]*profSample
+	funcs   map[string]*profFunc
+	lines   map[profLoc]*profLine
+}
+
+// NewProfiler returns a profiler for the program read from file, which is
+// only used to label the profile's functions.
+func NewProfiler(file string) *Profiler {
+	return &Profiler{
+		file:    file,
+		now:     time.Now,
+		samples: make(map[string]*profSample),
+		funcs:   make(map[string]*profFunc),
+		lines:   make(map[profLoc]*profLine),
+	}
+}
+
+// Stmt counts the statement about to execute.
+func (p *Profiler) Stmt(r *Runtime, n AstStmtNode) {
+	p.charge(r)
+	p.samples[p.key].count++
+	p.line(p.stack[0]).count++
+}
+
+// Enter counts a call to the function of frame f.
+func (p *Profiler) Enter(r *Runtime, f *Frame) {
+	p.charge(r)
+	p.function(f.Name).calls++
+}
+
+// Leave charges the time spent before the function of frame f returned.
+func (p *Profiler) Leave(r *Runtime, f *Frame) {
+	p.charge(r)
+}
+
+// charge attributes the time elapsed since the previous event to the call
+// stack that was then current, and records the runtime's current stack.
+func (p *Profiler) charge(r *Runtime) {
+	now := p.now()
+	if p.start.IsZero() {
+		p.start = now
+	}
+	if p.stack != nil {
+		elapsed := now.Sub(p.last).Nanoseconds()
+		p.samples[p.key].nanos += elapsed
+		p.line(p.stack[0]).nanos += elapsed
+		p.function(p.stack[0].fn).flat += elapsed
+
+		seen := make(map[string]bool)
+		for _, loc := range p.stack {
+			if !seen[loc.fn] {
+				seen[loc.fn] = true
+				p.function(loc.fn).cum += elapsed
+			}
+		}
+	}
+	p.last = now
+
+	frames := r.Frames()
+	p.stack = make([]profLoc, len(frames))
+	keys := make([]string, len(frames))
+	for i, f := range frames {
+		loc := profLoc{f.Name, f.Pos.Line}
+		p.stack[len(frames)-1-i] = loc
+		keys[len(frames)-1-i] = fmt.Sprintf("%s:%d", loc.fn, loc.line)
+	}
+	if len(frames) == 0 {
+		p.stack = nil
+		return
+	}
+	p.key = strings.Join(keys, ";")
+	if _, ok := p.samples[p.key]; !ok {
+		p.samples[p.key] = &profSample{stack: p.stack}
+	}
+}
+
+func (p *Profiler) function(name string) *profFunc {
+	f, ok := p.funcs[name]
+	if !ok {
+		f = &profFunc{name: name}
+		p.funcs[name] = f
+	}
+	return f
+}
+
+func (p *Profiler) line(loc profLoc) *profLine {
+	l, ok := p.lines[loc]
+	if !ok {
+		l = &profLine{loc: loc}
+		p.lines[loc] = l
+	}
+	return l
+}
+
+// WriteSummary writes the n most expensive functions and source lines as
+// plain text.
+func (p *Profiler) WriteSummary(w io.Writer, n int) {
+	var funcs []*profFunc
+	for _, f := range p.funcs {
+		funcs = append(funcs, f)
+	}
+	sort.Slice(funcs, func(i, j int) bool {
+		if funcs[i].flat != funcs[j].flat {
+			return funcs[i].flat > funcs[j].flat
+		}
+		return funcs[i].name < funcs[j].name
+	})
+
+	var lines []*profLine
+	for _, l := range p.lines {
+		lines = append(lines, l)
+	}
+	sort.Slice(lines, func(i, j int) bool {
+		if lines[i].nanos != lines[j].nanos {
+			return lines[i].nanos > lines[j].nanos
+		}
+		if lines[i].loc.line != lines[j].loc.line {
+			return lines[i].loc.line < lines[j].loc.line
+		}
+		return lines[i].loc.fn < lines[j].loc.fn
+	})
+
+	fmt.Fprintf(w, "%12s %12s %12s  %s\n", "calls", "flat", "cum", "function")
+	for i, f := range funcs {
+		if i == n {
+			break
+		}
+		fmt.Fprintf(w, "%12d %12s %12s  %s\n", f.calls,
+			time.Duration(f.flat), time.Duration(f.cum), f.name)
+	}
+	fmt.Fprintln(w)
+	fmt.Fprintf(w, "%12s %12s  %s\n", "count", "time", "line")
+	for i, l := range lines {
+		if i == n {
+			break
+		}
+		fmt.Fprintf(w, "%12d %12s  %s:%d (%s)\n", l.count,
+			time.Duration(l.nanos), p.file, l.loc.line, l.loc.fn)
+	}
+}
+
+// WritePprof writes the profile in the gzipped protocol buffer format read
+// by `go tool pprof`. Each sample carries two values: the number of
+// statements executed and the nanoseconds spent.
+func (p *Profiler) WritePprof(w io.Writer) error {
+	strs := map[string]int{"": 0}
+	table := []string{""}
+	str := func(s string) uint64 {
+		i, ok := strs[s]
+		if !ok {
+			i = len(table)
+			strs[s] = i
+			table = append(table, s)
+		}
+		return uint64(i)
+	}
+
+	var prof protoBuf
+	valueType := func(typ, unit string) []byte {
+		var vt protoBuf
+		vt.uint(1, str(typ))
+		vt.uint(2, str(unit))
+		return vt
+	}
+	prof.bytes(1, valueType("statements", "count"))
+	prof.bytes(1, valueType("time", "nanoseconds"))
+
+	funcIDs := make(map[string]uint64)
+	locIDs := make(map[profLoc]uint64)
+	var funcs, locs []byte
+
+	var keys []string
+	for key := range p.samples {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		s := p.samples[key]
+		var ids []uint64
+		for _, loc := range s.stack {
+			fid, ok := funcIDs[loc.fn]
+			if !ok {
+				fid = uint64(len(funcIDs) + 1)
+				funcIDs[loc.fn] = fid
+				var fn protoBuf
+				fn.uint(1, fid)
+				fn.uint(2, str(loc.fn))
+				fn.uint(3, str(loc.fn))
+				fn.uint(4, str(p.file))
+				funcs = appendField(funcs, 5, fn)
+			}
+			lid, ok := locIDs[loc]
+			if !ok {
+				lid = uint64(len(locIDs) + 1)
+				locIDs[loc] = lid
+				var line protoBuf
+				line.uint(1, fid)
+				line.uint(2, uint64(loc.line))
+				var l protoBuf
+				l.uint(1, lid)
+				l.bytes(4, line)
+				locs = appendField(locs, 4, l)
+			}
+			ids = append(ids, lid)
+		}
+
+		var sample protoBuf
+		sample.packed(1, ids)
+		sample.packed(2, []uint64{uint64(s.count), uint64(s.nanos)})
+		prof.bytes(2, sample)
+	}
+	prof = append(prof, locs...)
+	prof = append(prof, funcs...)
+
+	var period protoBuf
+	period.uint(1, str("time"))
+	period.uint(2, str("nanoseconds"))
+	for _, s := range table {
+		prof.bytes(6, []byte(s))
+	}
+	prof.uint(9, uint64(p.start.UnixNano()))
+	prof.uint(10, uint64(p.last.Sub(p.start).Nanoseconds()))
+	prof.bytes(11, period)
+	prof.uint(12, 1)
+
+	gz := gzip.NewWriter(w)
+	if _, err := gz.Write(prof); err != nil {
+		return err
+	}
+	return gz.Close()
+}
+
+// protoBuf is a minimal protocol buffer encoder, sufficient for writing
+// profiles.
+type protoBuf []byte
+
+func (b *protoBuf) varint(x uint64) {
+	for x >= 0x80 {
+		*b = append(*b, byte(x)|0x80)
+		x >>= 7
+	}
+	*b = append(*b, byte(x))
+}
+
+// uint writes a varint field. Zero values are omitted as in proto3.
+func (b *protoBuf) uint(field int, x uint64) {
+	if x == 0 {
+		return
+	}
+	b.varint(uint64(field)<<3 | 0)
+	b.varint(x)
+}
+
+// bytes writes a length-delimited field.
+func (b *protoBuf) bytes(field int, data []byte) {
+	b.varint(uint64(field)<<3 | 2)
+	b.varint(uint64(len(data)))
+	*b = append(*b, data...)
+}
+
+// packed writes a packed repeated varint field.
+func (b *protoBuf) packed(field int, xs []uint64) {
+	var data protoBuf
+	for _, x := range xs {
+		data.varint(x)
+	}
+	b.bytes(field, data)
+}
+
+// appendField appends a length-delimited field to dst.
+func appendField(dst []byte, field int, data []byte) []byte {
+	b := protoBuf(dst)
+	b.bytes(field, data)
+	return b
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io/ioutil"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func profileSource(src string) *Profiler {
+	prog, _ := newParser(src).Parse()
+	p := NewProfiler("test.kw")
+	clock := time.Unix(0, 0)
+	p.now = func() time.Time {
+		clock = clock.Add(time.Millisecond)
+		return clock
+	}
+	r := NewRuntime(testRuntimeEnv(""))
+	r.AddTracer(p)
+	prog.Accept(r)
+	return p
+}
+
+func TestProfiler(t *testing.T) {
+	t.Parallel()
+
+	src := "func foo {\n  return 1\n}\ni := 0\nwhile i < 3 {\n  i := i + foo()\n}\n"
+
+	t.Run("Count calls and statements", func(t *testing.T) {
+		p := profileSource(src)
+		assert.Equal(t, int64(3), p.funcs["foo"].calls)
+		assert.Equal(t, int64(1), p.funcs["main"].calls)
+		assert.Equal(t, int64(3), p.lines[profLoc{"foo", 2}].count)
+		assert.Equal(t, int64(3), p.lines[profLoc{"main", 6}].count)
+		assert.Equal(t, int64(1), p.lines[profLoc{"main", 5}].count)
+	})
+
+	t.Run("Charge time to stacks", func(t *testing.T) {
+		p := profileSource(src)
+		var total int64
+		for _, s := range p.samples {
+			total += s.nanos
+		}
+		assert.Equal(t, p.last.Sub(p.start).Nanoseconds(), total)
+		assert.True(t, p.funcs["main"].cum >= p.funcs["foo"].cum)
+		assert.Equal(t, p.funcs["foo"].flat, p.funcs["foo"].cum)
+	})
+
+	t.Run("Write summary", func(t *testing.T) {
+		p := profileSource(src)
+		buf := &bytes.Buffer{}
+		p.WriteSummary(buf, 1)
+		out := buf.String()
+		assert.Contains(t, out, "function")
+		assert.Contains(t, out, "test.kw:")
+		assert.Equal(t, 5, bytes.Count(buf.Bytes(), []byte("\n")))
+	})
+
+	t.Run("Write pprof", func(t *testing.T) {
+		p := profileSource(src)
+		buf := &bytes.Buffer{}
+		assert.Nil(t, p.WritePprof(buf))
+
+		gz, err := gzip.NewReader(buf)
+		assert.Nil(t, err)
+		data, err := ioutil.ReadAll(gz)
+		assert.Nil(t, err)
+		for _, s := range []string{"statements", "nanoseconds", "foo", "main", "test.kw"} {
+			assert.Contains(t, string(data), s)
+		}
+	})
+
+	t.Run("Encode varints", func(t *testing.T) {
+		var b protoBuf
+		b.uint(1, 300)
+		assert.Equal(t, protoBuf{0x08, 0xac, 0x02}, b)
+	})
+}
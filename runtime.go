@@ -1,9 +1,14 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"io"
-	"math"
+	"io/ioutil"
+	"math/big"
+	"math/rand"
+	"runtime"
 	"strconv"
 	"strings"
 )
@@ -14,57 +19,371 @@ type (
 		scopeStack Stack
 		currScope  *Scope
 		env        *RuntimeEnv
+		frames     []*Frame
+		tracers    []Tracer
+		returning  bool
+		nodes      int
+		stopPos    Pos
 	}
 
+	// RuntimeEnv is the environment a program runs in. The limits bound
+	// the resources a program may use; a zero limit is no limit.
 	RuntimeEnv struct {
 		stdin  io.Reader
 		stdout io.Writer
 		stderr io.Writer
+
+		maxNodes int // number of nodes evaluated
+		maxDepth int // depth of nested function calls
+		maxSize  int // length of a string or collection
+
+		// decScale is the least number of fractional digits in the
+		// quotient of a decimal division, or defaultDecScale if it is
+		// zero, and decRounding how the quotient is rounded to its scale.
+		// A program sets them with the decimal_scale builtin.
+		decScale    int
+		decRounding RoundingMode
+
+		// caps are the capabilities granted to builtins. readPaths and
+		// writePaths, if not empty, are the only directories whose files
+		// may be read and written.
+		caps       Capability
+		readPaths  []string
+		writePaths []string
+
+		// rng generates the values of the random builtins. It is seeded
+		// from the clock when first used unless it is set beforehand.
+		rng *rand.Rand
+
+		// clock tells the time builtins the time. The system clock is
+		// used if it is nil.
+		clock Clock
+
+		// args are the command-line arguments given to the program.
+		args []string
+
+		// regexps caches the patterns compiled by the regular expression
+		// builtins for the Runtime that owns this environment.
+		regexps *regexpCache
+
+		// ctx is the context of the running program, or nil when it
+		// cannot be interrupted.
+		ctx context.Context
+	}
+
+	// InterruptError reports that a program was stopped at Pos because
+	// its context was done. Err is the context's error.
+	InterruptError struct {
+		Pos Pos
+		Err error
+	}
+
+	// ExitError reports that a program asked to exit with the status Code.
+	ExitError struct {
+		Code int
 	}
 
 	params []ScopeEntry
+
+	// Frame records an active function call. Pos is the position of the
+	// statement currently executing in the frame.
+	Frame struct {
+		Name  string
+		Pos   Pos
+		Scope *Scope
+	}
+
+	// Tracer observes the execution of a program. Stmt is called before
+	// each statement executes, and Enter and Leave bracket each function
+	// call.
+	Tracer interface {
+		Stmt(*Runtime, AstStmtNode)
+		Enter(*Runtime, *Frame)
+		Leave(*Runtime, *Frame)
+	}
+
+	// BranchTracer is a Tracer that is also told which way conditional
+	// statements go. Branch 0 is an if's body or another trip through a
+	// while loop, and branch 1 is an if's else clause or a loop's exit.
+	BranchTracer interface {
+		Tracer
+		Branch(*Runtime, AstStmtNode, int)
+	}
+)
+
+// Errors raised through the runtime when a program exceeds one of the
+// limits of its RuntimeEnv.
+var (
+	ErrNodeLimit  = errors.New("evaluation limit exceeded")
+	ErrDepthLimit = errors.New("call depth limit exceeded")
+	ErrSizeLimit  = errors.New("size limit exceeded")
 )
 
+// checkSize panics with ErrSizeLimit if a string or collection of length n
+// is larger than env allows.
+func (env *RuntimeEnv) checkSize(n int) {
+	if env != nil && env.maxSize > 0 && n > env.maxSize {
+		panic(ErrSizeLimit)
+	}
+}
+
+func (e *InterruptError) Error() string {
+	return fmt.Sprintf("%d:%d: %v", e.Pos.Line, e.Pos.Col, e.Err)
+}
+
+func (e *InterruptError) Unwrap() error {
+	return e.Err
+}
+
+func (e *ExitError) Error() string {
+	return fmt.Sprintf("exit status %d", e.Code)
+}
+
+// readAll reads from in until EOF, giving up early if the program's context
+// is done. A read that is given up on is left to finish in the background.
+func (env *RuntimeEnv) readAll(in io.Reader) ([]byte, error) {
+	if env.ctx == nil {
+		return ioutil.ReadAll(in)
+	}
+	type result struct {
+		b   []byte
+		err error
+	}
+	ch := make(chan result, 1)
+	go func() {
+		b, err := ioutil.ReadAll(in)
+		ch <- result{b, err}
+	}()
+	select {
+	case res := <-ch:
+		return res.b, res.err
+	case <-env.ctx.Done():
+		return nil, env.ctx.Err()
+	}
+}
+
+// defaultDecScale is the least number of fractional digits in the quotient
+// of a decimal division unless the environment sets another, so that 1d / 3
+// is 0.33 rather than 0.
+const defaultDecScale = 2
+
+// decDivision returns the scale and rounding mode of the quotient of
+// dividing the decimal a by b. The quotient has as many fractional digits
+// as the more precise of a and b, but no fewer than env's decScale.
+func (env *RuntimeEnv) decDivision(a, b Decimal) (int, RoundingMode) {
+	scale, mode, least := a.scale, RoundHalfEven, defaultDecScale
+	if b.scale > scale {
+		scale = b.scale
+	}
+	if env != nil {
+		mode = env.decRounding
+		if env.decScale > 0 {
+			least = env.decScale
+		}
+	}
+	if least > scale {
+		scale = least
+	}
+	return scale, mode
+}
+
+// NewRuntime returns a runtime for programs run in env. The runtime keeps
+// its own copy of env, holding its own cache of compiled patterns, so that
+// runtimes made from one environment share no state they may change.
 func NewRuntime(env *RuntimeEnv) *Runtime {
+	if env != nil {
+		own := *env
+		own.regexps = newRegexpCache()
+		env = &own
+	}
 	r := &Runtime{
-		NewStack(),
-		NewStack(),
-		NewScope(),
-		env,
+		stack:      NewStack(),
+		scopeStack: NewStack(),
+		currScope:  NewScope(),
+		env:        env,
 	}
 
-	for name, fn := range builtins {
-		r.currScope.SetFunc(name, ScopeEntry{TypBuiltin, fn})
+	for name, b := range builtins {
+		r.currScope.SetFunc(name, ScopeEntry{TypBuiltin, b.fn})
 	}
 	return r
 }
 
+// Run runs prog until it finishes or ctx is done, returning any error raised
+// by the program. If ctx is done first, the error is an *InterruptError
+// giving the position where the program stopped. A program that calls exit
+// returns an *ExitError with its status. Whatever the error, the calls in
+// progress are unwound as though they had returned, leaving r ready to run
+// again, and StopPos gives the position of the statement that raised it. A
+// Go runtime error is an interpreter bug rather than the program's, and
+// panics again. The context is checked before each trip through a loop and
+// before each function call, and interrupts a read from the program's
+// input.
+func (r *Runtime) Run(ctx context.Context, prog *AstProgramNode) (err error) {
+	env := RuntimeEnv{}
+	if r.env != nil {
+		env = *r.env
+	}
+	env.ctx = ctx
+	r.env = &env
+
+	depth := r.scopeStack.Size()
+
+	defer func() {
+		e := recover()
+		if e == nil {
+			return
+		}
+		if re, ok := e.(runtime.Error); ok {
+			panic(re)
+		}
+		if len(r.frames) > 0 {
+			r.stopPos = r.frames[len(r.frames)-1].Pos
+		}
+		switch e := e.(type) {
+		case *InterruptError, *ExitError:
+			err = e.(error)
+		case error:
+			err = e
+			if errors.Is(e, context.Canceled) || errors.Is(e, context.DeadlineExceeded) {
+				err = &InterruptError{r.stopPos, e}
+			}
+		case string:
+			err = errors.New(e)
+		default:
+			panic(e)
+		}
+		r.unwind(depth)
+	}()
+	prog.Accept(r)
+	return nil
+}
+
+// unwind ends the calls of a program that stopped while they were running,
+// popping their frames so that tracers see each call end, and restores the
+// scopes to the depth they had before the program ran.
+func (r *Runtime) unwind(depth int) {
+	for len(r.frames) > 0 {
+		r.popFrame()
+	}
+	for r.scopeStack.Size() > depth {
+		r.currScope = r.scopeStack.Pop().(*Scope)
+	}
+	r.stack = NewStack()
+	r.returning = false
+}
+
+// StopPos returns the position of the statement that was running when the
+// most recent Run stopped with an error.
+func (r *Runtime) StopPos() Pos {
+	return r.stopPos
+}
+
+// interrupt stops the program at pos if its context is done.
+func (r *Runtime) interrupt(pos Pos) {
+	if r.env == nil || r.env.ctx == nil {
+		return
+	}
+	if err := r.env.ctx.Err(); err != nil {
+		panic(&InterruptError{pos, err})
+	}
+}
+
+// AddTracer registers t to observe the runtime's execution.
+func (r *Runtime) AddTracer(t Tracer) {
+	r.tracers = append(r.tracers, t)
+}
+
+// Frames returns the active call frames, outermost first.
+func (r *Runtime) Frames() []*Frame {
+	return r.frames
+}
+
+// eval evaluates the node n, counting it against the runtime's node limit.
+func (r *Runtime) eval(n AstNode) {
+	r.nodes++
+	if r.env != nil && r.env.maxNodes > 0 && r.nodes > r.env.maxNodes {
+		panic(ErrNodeLimit)
+	}
+	n.Accept(r)
+}
+
+// exec evaluates the statement n, first updating the current frame's
+// position and notifying any tracers. Unless n returns from a function,
+// any value it leaves on the stack, such as the result of a function called
+// for its side effects, is discarded.
+func (r *Runtime) exec(n AstNode) {
+	if s, ok := n.(AstStmtNode); ok && len(r.frames) > 0 {
+		r.frames[len(r.frames)-1].Pos = s.Position()
+		for _, t := range r.tracers {
+			t.Stmt(r, s)
+		}
+	}
+	size := r.stack.Size()
+	r.eval(n)
+	if !r.returning && r.stack.Size() > size {
+		r.stack = r.stack[:size]
+	}
+}
+
+// branch notifies tracers that follow branches that the conditional
+// statement n took the given branch.
+func (r *Runtime) branch(n AstStmtNode, taken int) {
+	for _, t := range r.tracers {
+		if bt, ok := t.(BranchTracer); ok {
+			bt.Branch(r, n, taken)
+		}
+	}
+}
+
+// pushFrame makes f the current call frame.
+func (r *Runtime) pushFrame(f *Frame) {
+	r.frames = append(r.frames, f)
+	for _, t := range r.tracers {
+		t.Enter(r, f)
+	}
+}
+
+// popFrame discards the current call frame.
+func (r *Runtime) popFrame() {
+	f := r.frames[len(r.frames)-1]
+	for _, t := range r.tracers {
+		t.Leave(r, f)
+	}
+	r.frames = r.frames[:len(r.frames)-1]
+}
+
 func (r *Runtime) VisitAddNode(n *AstAddNode) {
-	n.Left.Accept(r)
+	r.eval(n.Left)
 	left := r.stack.Pop().(ScopeEntry)
 
-	n.Right.Accept(r)
+	r.eval(n.Right)
 	right := r.stack.Pop().(ScopeEntry)
 
 	if left.DataType == TypNumber && right.DataType == TypNumber {
 		r.stack.Push(ScopeEntry{
 			TypNumber,
-			left.Value.(float64) + right.Value.(float64),
+			numAdd(left.Value, right.Value),
 		})
 		return
 	}
 	if left.DataType == TypString && right.DataType == TypString {
+		r.env.checkSize(len(left.Value.(string)) + len(right.Value.(string)))
 		r.stack.Push(ScopeEntry{
 			TypString,
 			left.Value.(string) + right.Value.(string),
 		})
 		return
 	}
+	if a, b, ok := decOperands(left, right); ok {
+		r.stack.Push(ScopeEntry{TypDecimal, a.Add(b)})
+		return
+	}
 	panic("operation not permitted with type")
 }
 
 func (r *Runtime) VisitAndNode(n *AstAndNode) {
-	n.Left.Accept(r)
+	r.eval(n.Left)
 	left := r.stack.Pop().(ScopeEntry)
 	// short-circuit if false
 	if left.DataType == TypBool && !left.Value.(bool) {
@@ -72,7 +391,7 @@ func (r *Runtime) VisitAndNode(n *AstAndNode) {
 		return
 	}
 
-	n.Right.Accept(r)
+	r.eval(n.Right)
 	right := r.stack.Pop().(ScopeEntry)
 
 	if left.DataType == TypBool && right.DataType == TypBool {
@@ -86,7 +405,7 @@ func (r *Runtime) VisitAndNode(n *AstAndNode) {
 }
 
 func (r *Runtime) VisitAssignNode(n *AstAssignNode) {
-	n.Expr.Accept(r)
+	r.eval(n.Expr)
 	v := r.stack.Pop().(ScopeEntry)
 
 	// preserve datatype if the variable is already set
@@ -103,37 +422,54 @@ func (r *Runtime) VisitBoolNode(n *AstBoolNode) {
 	r.stack.Push(ScopeEntry{TypBool, n.Value})
 }
 
+// castStr returns the value of e cast to a string. Containers and null are
+// written as JSON, their size limited by env.
+func castStr(env *RuntimeEnv, e ScopeEntry) string {
+	switch e.DataType {
+	case TypNumber:
+		return numToStr(e.Value)
+	case TypDecimal:
+		return e.Value.(Decimal).String()
+	case TypBool:
+		return strconv.FormatBool(e.Value.(bool))
+	case TypList, TypMap, TypNull:
+		enc := &jsonEncoder{env: env}
+		if err := enc.encode(e, 0); err != nil {
+			panic(err.Error())
+		}
+		return enc.buf.String()
+	}
+	return e.Value.(string)
+}
+
 func (r *Runtime) VisitCastNode(n *AstCastNode) {
-	n.Term.Accept(r)
+	r.eval(n.Term)
 	e := r.stack.Pop().(ScopeEntry)
+	if e.DataType == TypList || e.DataType == TypMap || e.DataType == TypNull {
+		switch cast := strings.ToLower(n.Cast); cast {
+		case "num", "bool", "dec":
+			panic(fmt.Sprintf("cannot cast %s to %s", dataTypeName(e.DataType), cast))
+		}
+	}
 	switch strings.ToUpper(n.Cast) {
 	case "STR":
-		switch e.DataType {
-		case TypString:
-			break
-		case TypNumber:
-			val := fmt.Sprintf("%f", e.Value.(float64))
-			val = strings.TrimRight(val, "0")
-			val = strings.TrimRight(val, ".")
-			e.Value = val
-			break
-		case TypBool:
-			e.Value = strconv.FormatBool(e.Value.(bool))
-			break
-		}
+		e.Value = castStr(r.env, e)
 		e.DataType = TypString
 		break
 	case "NUM":
 		switch e.DataType {
 		case TypString:
-			e.Value, _ = strconv.ParseFloat(e.Value.(string), 64)
+			e.Value, _ = parseNum(e.Value.(string))
 			break
 		case TypNumber:
 			break
+		case TypDecimal:
+			e.Value = e.Value.(Decimal).toNum()
+			break
 		case TypBool:
-			val := 0.0
+			val := int64(0)
 			if e.Value.(bool) {
-				val = 1.0
+				val = 1
 			}
 			e.Value = val
 			break
@@ -148,52 +484,92 @@ func (r *Runtime) VisitCastNode(n *AstCastNode) {
 			e.Value = value
 			break
 		case TypNumber:
-			e.Value = e.Value.(float64) != 0.0
+			e.Value = !numIsZero(e.Value)
+			break
+		case TypDecimal:
+			e.Value = !e.Value.(Decimal).IsZero()
 			break
 		case TypBool:
 			break
 		}
 		e.DataType = TypBool
 		break
+	case "DEC":
+		switch e.DataType {
+		case TypString:
+			e.Value, _ = parseDec(e.Value.(string))
+			break
+		case TypNumber:
+			d, ok := numToDec(e.Value)
+			if !ok {
+				panic("number cannot be represented as a decimal")
+			}
+			e.Value = d
+			break
+		case TypDecimal:
+			break
+		case TypBool:
+			val := Decimal{new(big.Int), 0}
+			if e.Value.(bool) {
+				val.unscaled.SetInt64(1)
+			}
+			e.Value = val
+			break
+		}
+		e.DataType = TypDecimal
+		break
 	}
 	r.stack.Push(e)
 }
 
+func (r *Runtime) VisitDecimalNode(n *AstDecimalNode) {
+	r.stack.Push(ScopeEntry{TypDecimal, n.Value})
+}
+
 func (r *Runtime) VisitDivideNode(n *AstDivideNode) {
-	n.Left.Accept(r)
+	r.eval(n.Left)
 	left := r.stack.Pop().(ScopeEntry)
 
-	n.Right.Accept(r)
+	r.eval(n.Right)
 	right := r.stack.Pop().(ScopeEntry)
 
 	if left.DataType == TypNumber && right.DataType == TypNumber {
 		r.stack.Push(ScopeEntry{
 			TypNumber,
-			left.Value.(float64) / right.Value.(float64),
+			numDiv(left.Value, right.Value),
 		})
 		return
 	}
+	if a, b, ok := decOperands(left, right); ok {
+		scale, mode := r.env.decDivision(a, b)
+		r.stack.Push(ScopeEntry{TypDecimal, a.Quo(b, scale, mode)})
+		return
+	}
 	panic("operation not permitted with type")
 }
 
 func (r *Runtime) VisitEqualNode(n *AstEqualNode) {
-	n.Left.Accept(r)
+	r.eval(n.Left)
 	left := r.stack.Pop().(ScopeEntry)
 
-	n.Right.Accept(r)
+	r.eval(n.Right)
 	right := r.stack.Pop().(ScopeEntry)
 
-	if left.DataType == right.DataType {
-		r.stack.Push(ScopeEntry{
-			TypBool,
-			left.Value == right.Value,
-		})
+	// any value may be compared with null
+	if left.DataType == right.DataType || left.DataType == TypNull ||
+		right.DataType == TypNull {
+		r.stack.Push(ScopeEntry{TypBool, valuesEqual(left, right)})
+		return
+	}
+	if a, b, ok := decOperands(left, right); ok {
+		r.stack.Push(ScopeEntry{TypBool, a.Cmp(b) == 0})
 		return
 	}
 	panic("operation not permitted with type")
 }
 
 func (r *Runtime) VisitFuncCallNode(n *AstFuncCallNode) {
+	r.interrupt(n.Pos)
 	e, ok := r.currScope.GetFunc(n.Name)
 	if !ok {
 		panic("Function not defined")
@@ -201,12 +577,16 @@ func (r *Runtime) VisitFuncCallNode(n *AstFuncCallNode) {
 
 	var p params
 	for _, arg := range n.Args {
-		arg.Accept(r)
+		r.eval(arg)
 		p = append(p, r.stack.Pop().(ScopeEntry))
 	}
 
 	if e.DataType == TypBuiltin {
-		builtins[n.Name](&r.stack, p, r.env)
+		r.pushFrame(&Frame{Name: n.Name, Pos: n.Pos})
+		b := builtins[n.Name]
+		r.env.require(n.Name, b.caps)
+		b.fn(&r.stack, p, r.env)
+		r.popFrame()
 		return
 	}
 
@@ -215,17 +595,26 @@ func (r *Runtime) VisitFuncCallNode(n *AstFuncCallNode) {
 		panic("wrong number of arguments in function call")
 	}
 
+	// the main frame is at the bottom of the stack, so its height is the
+	// depth of the new call
+	if r.env != nil && r.env.maxDepth > 0 && len(r.frames) > r.env.maxDepth {
+		panic(ErrDepthLimit)
+	}
+
 	r.scopeStack.Push(r.currScope)
 	r.currScope = f.Scope.EmptyVarCopy()
 	for i, arg := range f.Args {
 		r.currScope.SetVar(arg, p[i])
 	}
+	r.pushFrame(&Frame{Name: n.Name, Pos: f.Pos, Scope: r.currScope})
 	for _, stmt := range f.Body {
-		stmt.Accept(r)
-		if r.stack.Size() > 0 {
+		r.exec(stmt)
+		if r.returning {
 			break
 		}
 	}
+	r.returning = false
+	r.popFrame()
 	r.currScope = r.scopeStack.Pop().(*Scope)
 }
 
@@ -234,160 +623,258 @@ func (r *Runtime) VisitFuncDefNode(n *AstFuncDefNode) {
 }
 
 func (r *Runtime) VisitGreaterEqualNode(n *AstGreaterEqualNode) {
-	n.Left.Accept(r)
+	r.eval(n.Left)
 	left := r.stack.Pop().(ScopeEntry)
 
-	n.Right.Accept(r)
+	r.eval(n.Right)
 	right := r.stack.Pop().(ScopeEntry)
 
 	if left.DataType == TypNumber && right.DataType == TypNumber {
-		r.stack.Push(ScopeEntry{
-			TypBool,
-			left.Value.(float64) >= right.Value.(float64),
-		})
+		c, ok := numCmp(left.Value, right.Value)
+		r.stack.Push(ScopeEntry{TypBool, ok && c >= 0})
+		return
+	}
+	if a, b, ok := decOperands(left, right); ok {
+		r.stack.Push(ScopeEntry{TypBool, a.Cmp(b) >= 0})
 		return
 	}
 	panic("operation not permitted with type")
 }
 
 func (r *Runtime) VisitGreaterNode(n *AstGreaterNode) {
-	n.Left.Accept(r)
+	r.eval(n.Left)
 	left := r.stack.Pop().(ScopeEntry)
 
-	n.Right.Accept(r)
+	r.eval(n.Right)
 	right := r.stack.Pop().(ScopeEntry)
 
 	if left.DataType == TypNumber && right.DataType == TypNumber {
-		r.stack.Push(ScopeEntry{
-			TypBool,
-			left.Value.(float64) > right.Value.(float64),
-		})
+		c, ok := numCmp(left.Value, right.Value)
+		r.stack.Push(ScopeEntry{TypBool, ok && c > 0})
+		return
+	}
+	if a, b, ok := decOperands(left, right); ok {
+		r.stack.Push(ScopeEntry{TypBool, a.Cmp(b) > 0})
 		return
 	}
 	panic("operation not permitted with type")
 }
 
 func (r *Runtime) VisitIfNode(n *AstIfNode) {
-	n.Cond.Accept(r)
+	r.eval(n.Cond)
 	cond := r.stack.Pop().(ScopeEntry)
 	if cond.DataType != TypBool {
 		panic("non-bool expression used as condition")
 	}
 	if cond.Value.(bool) {
+		r.branch(n, 0)
 		for _, stmt := range n.Body {
-			stmt.Accept(r)
-			if r.stack.Size() > 0 {
+			r.exec(stmt)
+			if r.returning {
 				break
 			}
 		}
-	} else if n.Else != nil {
+	} else {
+		r.branch(n, 1)
 		for _, stmt := range n.Else {
-			stmt.Accept(r)
-			if r.stack.Size() > 0 {
+			r.exec(stmt)
+			if r.returning {
 				break
 			}
 		}
 	}
 }
 
+// evalIndex evaluates n, an index into a string or list of the given
+// length, and returns it as written and as an offset from the start. A
+// negative index counts back from the end. The offset is -1 if the index is
+// beyond either end.
+func (r *Runtime) evalIndex(n AstNode, kind string, length int) (string, int) {
+	r.eval(n)
+	e := r.stack.Pop().(ScopeEntry)
+	if e.DataType != TypNumber || !isInt(e.Value) {
+		panic(kind + " index must be an integer")
+	}
+	i, ok := e.Value.(int64)
+	if !ok || i < -int64(length) || i > int64(length) {
+		return numToStr(e.Value), -1
+	}
+	if i < 0 {
+		i += int64(length)
+	}
+	return numToStr(e.Value), int(i)
+}
+
+func (r *Runtime) VisitIndexNode(n *AstIndexNode) {
+	r.eval(n.Term)
+	e := r.stack.Pop().(ScopeEntry)
+
+	if e.DataType == TypMap {
+		r.eval(n.Index)
+		key := r.stack.Pop().(ScopeEntry)
+		if key.DataType != TypString {
+			panic("map key must be a string")
+		}
+		v, ok := e.Value.(*Map).Get(key.Value.(string))
+		if !ok {
+			panic(fmt.Sprintf("key %q not found in map", key.Value.(string)))
+		}
+		r.stack.Push(v)
+		return
+	}
+
+	var kind string
+	var length int
+	switch e.DataType {
+	case TypString:
+		e.Value = []rune(e.Value.(string))
+		kind, length = "string", len(e.Value.([]rune))
+	case TypList:
+		kind, length = "list", len(e.Value.([]ScopeEntry))
+	default:
+		panic("only strings, lists and maps can be indexed")
+	}
+	given, i := r.evalIndex(n.Index, kind, length)
+	if i < 0 || i >= length {
+		panic(fmt.Sprintf("index %s out of range for %s of length %d",
+			given, kind, length))
+	}
+	if e.DataType == TypList {
+		r.stack.Push(e.Value.([]ScopeEntry)[i])
+		return
+	}
+	r.stack.Push(ScopeEntry{TypString, string(e.Value.([]rune)[i])})
+}
+
+func (r *Runtime) VisitInterpNode(n *AstInterpNode) {
+	var buf strings.Builder
+	for i, expr := range n.Exprs {
+		buf.WriteString(n.Strs[i])
+		r.eval(expr)
+		buf.WriteString(castStr(r.env, r.stack.Pop().(ScopeEntry)))
+		r.env.checkSize(buf.Len())
+	}
+	buf.WriteString(n.Strs[len(n.Exprs)])
+	r.env.checkSize(buf.Len())
+	r.stack.Push(ScopeEntry{TypString, buf.String()})
+}
+
 func (r *Runtime) VisitLessEqualNode(n *AstLessEqualNode) {
-	n.Left.Accept(r)
+	r.eval(n.Left)
 	left := r.stack.Pop().(ScopeEntry)
 
-	n.Right.Accept(r)
+	r.eval(n.Right)
 	right := r.stack.Pop().(ScopeEntry)
 
 	if left.DataType == TypNumber && right.DataType == TypNumber {
-		r.stack.Push(ScopeEntry{
-			TypBool,
-			left.Value.(float64) <= right.Value.(float64),
-		})
+		c, ok := numCmp(left.Value, right.Value)
+		r.stack.Push(ScopeEntry{TypBool, ok && c <= 0})
+		return
+	}
+	if a, b, ok := decOperands(left, right); ok {
+		r.stack.Push(ScopeEntry{TypBool, a.Cmp(b) <= 0})
 		return
 	}
 	panic("operation not permitted with type")
 }
 
 func (r *Runtime) VisitLessNode(n *AstLessNode) {
-	n.Left.Accept(r)
+	r.eval(n.Left)
 	left := r.stack.Pop().(ScopeEntry)
 
-	n.Right.Accept(r)
+	r.eval(n.Right)
 	right := r.stack.Pop().(ScopeEntry)
 
 	if left.DataType == TypNumber && right.DataType == TypNumber {
-		r.stack.Push(ScopeEntry{
-			TypBool,
-			left.Value.(float64) < right.Value.(float64),
-		})
+		c, ok := numCmp(left.Value, right.Value)
+		r.stack.Push(ScopeEntry{TypBool, ok && c < 0})
+		return
+	}
+	if a, b, ok := decOperands(left, right); ok {
+		r.stack.Push(ScopeEntry{TypBool, a.Cmp(b) < 0})
 		return
 	}
 	panic("operation not permitted with type")
 }
 
 func (r *Runtime) VisitModuloNode(n *AstModuloNode) {
-	n.Left.Accept(r)
+	r.eval(n.Left)
 	left := r.stack.Pop().(ScopeEntry)
 
-	n.Right.Accept(r)
+	r.eval(n.Right)
 	right := r.stack.Pop().(ScopeEntry)
 
 	if left.DataType == TypNumber && right.DataType == TypNumber {
 		r.stack.Push(ScopeEntry{
 			TypNumber,
-			math.Mod(left.Value.(float64), right.Value.(float64)),
+			numMod(left.Value, right.Value),
 		})
 		return
 	}
+	if a, b, ok := decOperands(left, right); ok {
+		r.stack.Push(ScopeEntry{TypDecimal, a.Rem(b)})
+		return
+	}
 	panic("operation not permitted with type")
 }
 
 func (r *Runtime) VisitMultiplyNode(n *AstMultiplyNode) {
-	n.Left.Accept(r)
+	r.eval(n.Left)
 	left := r.stack.Pop().(ScopeEntry)
 
-	n.Right.Accept(r)
+	r.eval(n.Right)
 	right := r.stack.Pop().(ScopeEntry)
 
 	if left.DataType == TypNumber && right.DataType == TypNumber {
 		r.stack.Push(ScopeEntry{
 			TypNumber,
-			left.Value.(float64) * right.Value.(float64),
+			numMul(left.Value, right.Value),
 		})
 		return
 	}
+	if a, b, ok := decOperands(left, right); ok {
+		r.stack.Push(ScopeEntry{TypDecimal, a.Mul(b)})
+		return
+	}
 	panic("operation not permitted with type")
 }
 
 func (r *Runtime) VisitNegativeNode(n *AstNegativeNode) {
-	n.Term.Accept(r)
+	r.eval(n.Term)
 	e := r.stack.Pop().(ScopeEntry)
 
 	if e.DataType == TypNumber {
-		r.stack.Push(ScopeEntry{TypNumber, -e.Value.(float64)})
+		r.stack.Push(ScopeEntry{TypNumber, numNeg(e.Value)})
+		return
+	}
+	if e.DataType == TypDecimal {
+		r.stack.Push(ScopeEntry{TypDecimal, e.Value.(Decimal).Neg()})
 		return
 	}
 	panic("operation not permitted with type")
 }
 
 func (r *Runtime) VisitNotEqualNode(n *AstNotEqualNode) {
-	n.Left.Accept(r)
+	r.eval(n.Left)
 	left := r.stack.Pop().(ScopeEntry)
 
-	n.Right.Accept(r)
+	r.eval(n.Right)
 	right := r.stack.Pop().(ScopeEntry)
 
-	if left.DataType == right.DataType {
-		r.stack.Push(ScopeEntry{
-			TypBool,
-			left.Value != right.Value,
-		})
+	if left.DataType == right.DataType || left.DataType == TypNull ||
+		right.DataType == TypNull {
+		r.stack.Push(ScopeEntry{TypBool, !valuesEqual(left, right)})
+		return
+	}
+	if a, b, ok := decOperands(left, right); ok {
+		r.stack.Push(ScopeEntry{TypBool, a.Cmp(b) != 0})
 		return
 	}
 	panic("operation not permitted with type")
 }
 
 func (r *Runtime) VisitNotNode(n *AstNotNode) {
-	n.Term.Accept(r)
+	r.eval(n.Term)
 	e := r.stack.Pop().(ScopeEntry)
 
 	if e.DataType == TypBool {
@@ -402,7 +889,7 @@ func (r *Runtime) VisitNumberNode(n *AstNumberNode) {
 }
 
 func (r *Runtime) VisitOrNode(n *AstOrNode) {
-	n.Left.Accept(r)
+	r.eval(n.Left)
 	left := r.stack.Pop().(ScopeEntry)
 	// short-circuit if true
 	if left.DataType == TypBool && left.Value.(bool) {
@@ -410,7 +897,7 @@ func (r *Runtime) VisitOrNode(n *AstOrNode) {
 		return
 	}
 
-	n.Right.Accept(r)
+	r.eval(n.Right)
 	right := r.stack.Pop().(ScopeEntry)
 
 	if left.DataType == TypBool && right.DataType == TypBool {
@@ -424,11 +911,40 @@ func (r *Runtime) VisitOrNode(n *AstOrNode) {
 }
 
 func (r *Runtime) VisitPositiveNode(n *AstPositiveNode) {
-	n.Term.Accept(r)
+	r.eval(n.Term)
 	e := r.stack.Pop().(ScopeEntry)
 
 	if e.DataType == TypNumber {
-		r.stack.Push(ScopeEntry{TypNumber, math.Abs(e.Value.(float64))})
+		r.stack.Push(ScopeEntry{TypNumber, numAbs(e.Value)})
+		return
+	}
+	if e.DataType == TypDecimal {
+		r.stack.Push(ScopeEntry{TypDecimal, e.Value.(Decimal).Abs()})
+		return
+	}
+	panic("operation not permitted with type")
+}
+
+func (r *Runtime) VisitPowerNode(n *AstPowerNode) {
+	r.eval(n.Left)
+	left := r.stack.Pop().(ScopeEntry)
+
+	r.eval(n.Right)
+	right := r.stack.Pop().(ScopeEntry)
+
+	if left.DataType == TypNumber && right.DataType == TypNumber {
+		r.stack.Push(ScopeEntry{
+			TypNumber,
+			numPow(left.Value, right.Value),
+		})
+		return
+	}
+	if left.DataType == TypDecimal && right.DataType == TypNumber {
+		exp, ok := right.Value.(int64)
+		if !ok || exp < 0 {
+			panic("decimal exponent must be a non-negative integer")
+		}
+		r.stack.Push(ScopeEntry{TypDecimal, left.Value.(Decimal).Pow(exp)})
 		return
 	}
 	panic("operation not permitted with type")
@@ -438,16 +954,60 @@ func (r *Runtime) VisitProgramNode(n *AstProgramNode) {
 	n.Scope.parent = r.currScope
 	r.scopeStack.Push(r.currScope)
 	r.currScope = n.Scope
+	r.pushFrame(&Frame{Name: "main", Pos: Pos{1, 1}, Scope: n.Scope})
 
 	for _, stmt := range n.Stmts {
-		stmt.Accept(r)
+		r.exec(stmt)
+		if r.returning {
+			break
+		}
 	}
+	r.returning = false
 
+	r.popFrame()
 	r.currScope = r.scopeStack.Pop().(*Scope)
 }
 
 func (r *Runtime) VisitReturnNode(n *AstReturnNode) {
-	n.Expr.Accept(r)
+	if n.Expr != nil {
+		r.eval(n.Expr)
+	}
+	r.returning = true
+}
+
+func (r *Runtime) VisitSliceNode(n *AstSliceNode) {
+	r.eval(n.Term)
+	e := r.stack.Pop().(ScopeEntry)
+
+	var kind string
+	var length int
+	switch e.DataType {
+	case TypString:
+		e.Value = []rune(e.Value.(string))
+		kind, length = "string", len(e.Value.([]rune))
+	case TypList:
+		kind, length = "list", len(e.Value.([]ScopeEntry))
+	default:
+		panic("only strings and lists can be sliced")
+	}
+	lowGiven, low := "", 0
+	if n.Low != nil {
+		lowGiven, low = r.evalIndex(n.Low, kind, length)
+	}
+	highGiven, high := "", length
+	if n.High != nil {
+		highGiven, high = r.evalIndex(n.High, kind, length)
+	}
+	if low < 0 || high < low {
+		panic(fmt.Sprintf("slice bounds %s:%s out of range for %s of length %d",
+			lowGiven, highGiven, kind, length))
+	}
+	if e.DataType == TypList {
+		// limit the capacity so appending to the slice copies it
+		r.stack.Push(ScopeEntry{TypList, e.Value.([]ScopeEntry)[low:high:high]})
+		return
+	}
+	r.stack.Push(ScopeEntry{TypString, string(e.Value.([]rune)[low:high])})
 }
 
 func (r *Runtime) VisitStringNode(n *AstStringNode) {
@@ -455,19 +1015,23 @@ func (r *Runtime) VisitStringNode(n *AstStringNode) {
 }
 
 func (r *Runtime) VisitSubtractNode(n *AstSubtractNode) {
-	n.Left.Accept(r)
+	r.eval(n.Left)
 	left := r.stack.Pop().(ScopeEntry)
 
-	n.Right.Accept(r)
+	r.eval(n.Right)
 	right := r.stack.Pop().(ScopeEntry)
 
 	if left.DataType == TypNumber && right.DataType == TypNumber {
 		r.stack.Push(ScopeEntry{
 			TypNumber,
-			left.Value.(float64) - right.Value.(float64),
+			numSub(left.Value, right.Value),
 		})
 		return
 	}
+	if a, b, ok := decOperands(left, right); ok {
+		r.stack.Push(ScopeEntry{TypDecimal, a.Sub(b)})
+		return
+	}
 	panic("operation not permitted with type")
 }
 
@@ -481,17 +1045,20 @@ func (r *Runtime) VisitVariableNode(n *AstVariableNode) {
 
 func (r *Runtime) VisitWhileNode(n *AstWhileNode) {
 	for {
-		n.Cond.Accept(r)
+		r.interrupt(n.Pos)
+		r.eval(n.Cond)
 		cond := r.stack.Pop().(ScopeEntry)
 		if cond.DataType != TypBool {
 			panic("non-bool expression used as condition")
 		}
 		if !cond.Value.(bool) {
+			r.branch(n, 1)
 			return
 		}
+		r.branch(n, 0)
 		for _, stmt := range n.Body {
-			stmt.Accept(r)
-			if r.stack.Size() > 0 {
+			r.exec(stmt)
+			if r.returning {
 				return
 			}
 		}
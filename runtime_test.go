@@ -1,7 +1,14 @@
 package main
 
 import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"runtime"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -14,13 +21,13 @@ func TestRuntime(t *testing.T) {
 
 		t.Run("Evaluate AddNode with numbers", func(t *testing.T) {
 			n := &AstAddNode{
-				Left:  &AstNumberNode{42},
-				Right: &AstNumberNode{73},
+				Left:  &AstNumberNode{int64(42)},
+				Right: &AstNumberNode{int64(73)},
 			}
 			r := NewRuntime(nil)
 			n.Accept(r)
 			e := r.stack.Pop().(ScopeEntry)
-			assert.Equal(t, 115.0, e.Value)
+			assert.Equal(t, int64(115), e.Value)
 			assert.Equal(t, TypNumber, e.DataType)
 		})
 
@@ -38,7 +45,7 @@ func TestRuntime(t *testing.T) {
 
 		t.Run("Evaluate AddNode with type error", func(t *testing.T) {
 			n := &AstAddNode{
-				Left:  &AstNumberNode{42},
+				Left:  &AstNumberNode{int64(42)},
 				Right: &AstBoolNode{true},
 			}
 			r := NewRuntime(nil)
@@ -76,7 +83,7 @@ func TestRuntime(t *testing.T) {
 
 		t.Run("Evaluate AndNode with type error", func(t *testing.T) {
 			n := &AstAndNode{
-				Left:  &AstNumberNode{42},
+				Left:  &AstNumberNode{int64(42)},
 				Right: &AstBoolNode{true},
 			}
 			r := NewRuntime(nil)
@@ -110,7 +117,7 @@ func TestRuntime(t *testing.T) {
 			r := NewRuntime(nil)
 			n.Accept(r)
 
-			n.Expr = &AstNumberNode{42}
+			n.Expr = &AstNumberNode{int64(42)}
 			assert.Panics(t, func() {
 				n.Accept(r)
 			})
@@ -128,16 +135,16 @@ func TestRuntime(t *testing.T) {
 				expctType DataType
 			}{
 				{"str", &AstStringNode{"foo"}, "foo", TypString},
-				{"str", &AstNumberNode{42}, "42", TypString},
+				{"str", &AstNumberNode{int64(42)}, "42", TypString},
 				{"str", &AstBoolNode{true}, "true", TypString},
-				{"num", &AstStringNode{"foo"}, 0.0, TypNumber},
-				{"num", &AstNumberNode{42}, 42.0, TypNumber},
-				{"num", &AstBoolNode{true}, 1.0, TypNumber},
+				{"num", &AstStringNode{"foo"}, int64(0), TypNumber},
+				{"num", &AstNumberNode{int64(42)}, int64(42), TypNumber},
+				{"num", &AstBoolNode{true}, int64(1), TypNumber},
 				{"bool", &AstStringNode{"foo"}, true, TypBool},
-				{"bool", &AstNumberNode{42}, true, TypBool},
+				{"bool", &AstNumberNode{int64(42)}, true, TypBool},
 				{"bool", &AstBoolNode{true}, true, TypBool},
 				{"bool", &AstStringNode{""}, false, TypBool},
-				{"bool", &AstNumberNode{0}, false, TypBool},
+				{"bool", &AstNumberNode{int64(0)}, false, TypBool},
 				{"bool", &AstBoolNode{false}, false, TypBool},
 			}
 			for _, d := range nodeData {
@@ -160,8 +167,8 @@ func TestRuntime(t *testing.T) {
 
 		t.Run("Evaluate DivideNode", func(t *testing.T) {
 			n := &AstDivideNode{
-				Left:  &AstNumberNode{110},
-				Right: &AstNumberNode{4},
+				Left:  &AstNumberNode{int64(110)},
+				Right: &AstNumberNode{int64(4)},
 			}
 			r := NewRuntime(nil)
 			n.Accept(r)
@@ -172,7 +179,7 @@ func TestRuntime(t *testing.T) {
 
 		t.Run("Evaluate DivideNode with type error", func(t *testing.T) {
 			n := &AstDivideNode{
-				Left:  &AstNumberNode{42},
+				Left:  &AstNumberNode{int64(42)},
 				Right: &AstBoolNode{true},
 			}
 			r := NewRuntime(nil)
@@ -199,7 +206,7 @@ func TestRuntime(t *testing.T) {
 
 		t.Run("Evaluate EqualNode with type error", func(t *testing.T) {
 			n := &AstEqualNode{
-				Left:  &AstNumberNode{42},
+				Left:  &AstNumberNode{int64(42)},
 				Right: &AstBoolNode{true},
 			}
 			r := NewRuntime(nil)
@@ -214,8 +221,8 @@ func TestRuntime(t *testing.T) {
 
 		t.Run("Evaluate GreaterEqualNode", func(t *testing.T) {
 			n := &AstGreaterEqualNode{
-				Left:  &AstNumberNode{1984},
-				Right: &AstNumberNode{1776},
+				Left:  &AstNumberNode{int64(1984)},
+				Right: &AstNumberNode{int64(1776)},
 			}
 			r := NewRuntime(nil)
 			n.Accept(r)
@@ -226,7 +233,7 @@ func TestRuntime(t *testing.T) {
 
 		t.Run("Evaluate GreaterEqualNode with type error", func(t *testing.T) {
 			n := &AstGreaterEqualNode{
-				Left:  &AstNumberNode{42},
+				Left:  &AstNumberNode{int64(42)},
 				Right: &AstBoolNode{true},
 			}
 			r := NewRuntime(nil)
@@ -241,8 +248,8 @@ func TestRuntime(t *testing.T) {
 
 		t.Run("Evaluate GreaterNode", func(t *testing.T) {
 			n := &AstGreaterNode{
-				Left:  &AstNumberNode{1984},
-				Right: &AstNumberNode{1776},
+				Left:  &AstNumberNode{int64(1984)},
+				Right: &AstNumberNode{int64(1776)},
 			}
 			r := NewRuntime(nil)
 			n.Accept(r)
@@ -253,23 +260,55 @@ func TestRuntime(t *testing.T) {
 
 		t.Run("Evaluate GreaterNode with type error", func(t *testing.T) {
 			n := &AstGreaterNode{
-				Left:  &AstNumberNode{42},
+				Left:  &AstNumberNode{int64(42)},
 				Right: &AstBoolNode{true},
 			}
-			r := NewRuntime(&RuntimeEnv{nil, nil, nil})
+			r := NewRuntime(&RuntimeEnv{})
 			assert.Panics(t, func() {
 				n.Accept(r)
 			})
 		})
 	})
 
+	t.Run("Test InterpNode", func(t *testing.T) {
+		t.Parallel()
+
+		t.Run("Evaluate InterpNode", func(t *testing.T) {
+			n := &AstInterpNode{
+				Strs: []string{"fib(", ") = ", " ", ""},
+				Exprs: []AstNode{
+					&AstNumberNode{int64(10)},
+					&AstNumberNode{int64(55)},
+					&AstBoolNode{true},
+				},
+			}
+			r := NewRuntime(nil)
+			n.Accept(r)
+
+			e := r.stack.Pop().(ScopeEntry)
+			assert.Equal(t, "fib(10) = 55 true", e.Value)
+			assert.Equal(t, TypString, e.DataType)
+		})
+
+		t.Run("Limit size", func(t *testing.T) {
+			n := &AstInterpNode{
+				Strs:  []string{"", "!"},
+				Exprs: []AstNode{&AstStringNode{"abc"}},
+			}
+			r := NewRuntime(&RuntimeEnv{maxSize: 3})
+			assert.PanicsWithValue(t, ErrSizeLimit, func() {
+				n.Accept(r)
+			})
+		})
+	})
+
 	t.Run("Test LessEqualNode", func(t *testing.T) {
 		t.Parallel()
 
 		t.Run("Evaluate LessEqualNode", func(t *testing.T) {
 			n := &AstLessEqualNode{
-				Left:  &AstNumberNode{1984},
-				Right: &AstNumberNode{1776},
+				Left:  &AstNumberNode{int64(1984)},
+				Right: &AstNumberNode{int64(1776)},
 			}
 			r := NewRuntime(nil)
 			n.Accept(r)
@@ -280,7 +319,7 @@ func TestRuntime(t *testing.T) {
 
 		t.Run("Evaluate LessEqualNode with type error", func(t *testing.T) {
 			n := &AstLessEqualNode{
-				Left:  &AstNumberNode{42},
+				Left:  &AstNumberNode{int64(42)},
 				Right: &AstBoolNode{true},
 			}
 			r := NewRuntime(nil)
@@ -295,8 +334,8 @@ func TestRuntime(t *testing.T) {
 
 		t.Run("Evaluate LessNode", func(t *testing.T) {
 			n := &AstLessNode{
-				Left:  &AstNumberNode{1984},
-				Right: &AstNumberNode{1776},
+				Left:  &AstNumberNode{int64(1984)},
+				Right: &AstNumberNode{int64(1776)},
 			}
 			r := NewRuntime(nil)
 			n.Accept(r)
@@ -307,7 +346,7 @@ func TestRuntime(t *testing.T) {
 
 		t.Run("Evaluate LessNode with type error", func(t *testing.T) {
 			n := &AstLessNode{
-				Left:  &AstNumberNode{42},
+				Left:  &AstNumberNode{int64(42)},
 				Right: &AstBoolNode{true},
 			}
 			r := NewRuntime(nil)
@@ -322,19 +361,19 @@ func TestRuntime(t *testing.T) {
 
 		t.Run("Evaluate ModuloNode", func(t *testing.T) {
 			n := &AstModuloNode{
-				Left:  &AstNumberNode{73},
-				Right: &AstNumberNode{42},
+				Left:  &AstNumberNode{int64(73)},
+				Right: &AstNumberNode{int64(42)},
 			}
 			r := NewRuntime(nil)
 			n.Accept(r)
 			e := r.stack.Pop().(ScopeEntry)
-			assert.Equal(t, 31.0, e.Value)
+			assert.Equal(t, int64(31), e.Value)
 			assert.Equal(t, TypNumber, e.DataType)
 		})
 
 		t.Run("Evaluate ModuloNode with type error", func(t *testing.T) {
 			n := &AstModuloNode{
-				Left:  &AstNumberNode{42},
+				Left:  &AstNumberNode{int64(42)},
 				Right: &AstBoolNode{true},
 			}
 			r := NewRuntime(nil)
@@ -349,19 +388,19 @@ func TestRuntime(t *testing.T) {
 
 		t.Run("Evaluate MultiplyNode", func(t *testing.T) {
 			n := &AstMultiplyNode{
-				Left:  &AstNumberNode{21},
-				Right: &AstNumberNode{2},
+				Left:  &AstNumberNode{int64(21)},
+				Right: &AstNumberNode{int64(2)},
 			}
 			r := NewRuntime(nil)
 			n.Accept(r)
 			e := r.stack.Pop().(ScopeEntry)
-			assert.Equal(t, 42.0, e.Value)
+			assert.Equal(t, int64(42), e.Value)
 			assert.Equal(t, TypNumber, e.DataType)
 		})
 
 		t.Run("Evaluate MultiplyNode with type error", func(t *testing.T) {
 			n := &AstMultiplyNode{
-				Left:  &AstNumberNode{42},
+				Left:  &AstNumberNode{int64(42)},
 				Right: &AstBoolNode{true},
 			}
 			r := NewRuntime(nil)
@@ -375,11 +414,11 @@ func TestRuntime(t *testing.T) {
 		t.Parallel()
 
 		t.Run("Evaluate NegativeNode", func(t *testing.T) {
-			n := &AstNegativeNode{&AstNumberNode{42}}
+			n := &AstNegativeNode{&AstNumberNode{int64(42)}}
 			r := NewRuntime(nil)
 			n.Accept(r)
 			e := r.stack.Pop().(ScopeEntry)
-			assert.Equal(t, -42.0, e.Value)
+			assert.Equal(t, int64(-42), e.Value)
 			assert.Equal(t, TypNumber, e.DataType)
 		})
 
@@ -409,7 +448,7 @@ func TestRuntime(t *testing.T) {
 
 		t.Run("Evaluate NotEqualNode with type error", func(t *testing.T) {
 			n := &AstNotEqualNode{
-				Left:  &AstNumberNode{42},
+				Left:  &AstNumberNode{int64(42)},
 				Right: &AstBoolNode{true},
 			}
 			r := NewRuntime(nil)
@@ -432,7 +471,7 @@ func TestRuntime(t *testing.T) {
 		})
 
 		t.Run("Evaluate NotNode with type error", func(t *testing.T) {
-			n := &AstNotNode{&AstNumberNode{42}}
+			n := &AstNotNode{&AstNumberNode{int64(42)}}
 			r := NewRuntime(nil)
 			assert.Panics(t, func() {
 				n.Accept(r)
@@ -468,7 +507,7 @@ func TestRuntime(t *testing.T) {
 
 		t.Run("Evaluate OrNode with type error", func(t *testing.T) {
 			n := &AstOrNode{
-				Left:  &AstNumberNode{42},
+				Left:  &AstNumberNode{int64(42)},
 				Right: &AstBoolNode{true},
 			}
 			r := NewRuntime(nil)
@@ -482,11 +521,11 @@ func TestRuntime(t *testing.T) {
 		t.Parallel()
 
 		t.Run("Evaluate PositiveNode", func(t *testing.T) {
-			n := &AstPositiveNode{&AstNumberNode{-42}}
+			n := &AstPositiveNode{&AstNumberNode{int64(-42)}}
 			r := NewRuntime(nil)
 			n.Accept(r)
 			e := r.stack.Pop().(ScopeEntry)
-			assert.Equal(t, 42.0, e.Value)
+			assert.Equal(t, int64(42), e.Value)
 			assert.Equal(t, TypNumber, e.DataType)
 		})
 
@@ -499,6 +538,56 @@ func TestRuntime(t *testing.T) {
 		})
 	})
 
+	t.Run("Test PowerNode", func(t *testing.T) {
+		t.Parallel()
+
+		t.Run("Evaluate PowerNode", func(t *testing.T) {
+			n := &AstPowerNode{
+				Left:  &AstNumberNode{int64(2)},
+				Right: &AstNumberNode{int64(10)},
+			}
+			r := NewRuntime(nil)
+			n.Accept(r)
+			e := r.stack.Pop().(ScopeEntry)
+			assert.Equal(t, int64(1024), e.Value)
+			assert.Equal(t, TypNumber, e.DataType)
+		})
+
+		t.Run("Evaluate PowerNode with decimal", func(t *testing.T) {
+			n := &AstPowerNode{
+				Left:  &AstDecimalNode{dec("1.5")},
+				Right: &AstNumberNode{int64(2)},
+			}
+			r := NewRuntime(nil)
+			n.Accept(r)
+			e := r.stack.Pop().(ScopeEntry)
+			assert.Equal(t, "2.25", e.Value.(Decimal).String())
+			assert.Equal(t, TypDecimal, e.DataType)
+		})
+
+		t.Run("Evaluate PowerNode with decimal error", func(t *testing.T) {
+			n := &AstPowerNode{
+				Left:  &AstDecimalNode{dec("1.5")},
+				Right: &AstNumberNode{0.5},
+			}
+			r := NewRuntime(nil)
+			assert.PanicsWithValue(t, "decimal exponent must be a non-negative integer", func() {
+				n.Accept(r)
+			})
+		})
+
+		t.Run("Evaluate PowerNode with type error", func(t *testing.T) {
+			n := &AstPowerNode{
+				Left:  &AstNumberNode{int64(2)},
+				Right: &AstBoolNode{true},
+			}
+			r := NewRuntime(nil)
+			assert.Panics(t, func() {
+				n.Accept(r)
+			})
+		})
+	})
+
 	t.Run("Test ReturnNode", func(t *testing.T) {
 		t.Parallel()
 
@@ -512,6 +601,14 @@ func TestRuntime(t *testing.T) {
 			assert.Equal(t, true, e.Value)
 			assert.Equal(t, TypBool, e.DataType)
 		})
+
+		t.Run("Evaluate ReturnNode without value", func(t *testing.T) {
+			n := &AstReturnNode{}
+			r := NewRuntime(nil)
+			n.Accept(r)
+			assert.Equal(t, 0, r.stack.Size())
+			assert.True(t, r.returning)
+		})
 	})
 
 	t.Run("Test SubtractNode", func(t *testing.T) {
@@ -519,19 +616,19 @@ func TestRuntime(t *testing.T) {
 
 		t.Run("Evaluate SubtractNode", func(t *testing.T) {
 			n := &AstSubtractNode{
-				Left:  &AstNumberNode{73},
-				Right: &AstNumberNode{42},
+				Left:  &AstNumberNode{int64(73)},
+				Right: &AstNumberNode{int64(42)},
 			}
 			r := NewRuntime(nil)
 			n.Accept(r)
 			e := r.stack.Pop().(ScopeEntry)
-			assert.Equal(t, 31.0, e.Value)
+			assert.Equal(t, int64(31), e.Value)
 			assert.Equal(t, TypNumber, e.DataType)
 		})
 
 		t.Run("Eval SubtractNode with type error", func(t *testing.T) {
 			n := &AstSubtractNode{
-				Left:  &AstNumberNode{42},
+				Left:  &AstNumberNode{int64(42)},
 				Right: &AstBoolNode{true},
 			}
 			r := NewRuntime(nil)
@@ -541,6 +638,90 @@ func TestRuntime(t *testing.T) {
 		})
 	})
 
+	t.Run("Test IndexNode", func(t *testing.T) {
+		t.Parallel()
+
+		index := func(str string, i int64) ScopeEntry {
+			n := &AstIndexNode{
+				Term:  &AstStringNode{str},
+				Index: &AstNumberNode{i},
+			}
+			r := NewRuntime(nil)
+			n.Accept(r)
+			return r.stack.Pop().(ScopeEntry)
+		}
+
+		t.Run("Evaluate IndexNode", func(t *testing.T) {
+			assert.Equal(t, ScopeEntry{TypString, "é"}, index("héllo", 1))
+			assert.Equal(t, ScopeEntry{TypString, "o"}, index("héllo", -1))
+			assert.Equal(t, ScopeEntry{TypString, "h"}, index("héllo", -5))
+		})
+
+		t.Run("Report out of range", func(t *testing.T) {
+			assert.PanicsWithValue(t, "index 5 out of range for string of length 5", func() {
+				index("héllo", 5)
+			})
+			assert.PanicsWithValue(t, "index -6 out of range for string of length 5", func() {
+				index("héllo", -6)
+			})
+			assert.PanicsWithValue(t, "index 0 out of range for string of length 0", func() {
+				index("", 0)
+			})
+		})
+
+		t.Run("Require a string and integer", func(t *testing.T) {
+			r := NewRuntime(nil)
+			assert.PanicsWithValue(t, "only strings, lists and maps can be indexed", func() {
+				r.eval(&AstIndexNode{Term: &AstNumberNode{int64(1)}, Index: &AstNumberNode{int64(0)}})
+			})
+			assert.PanicsWithValue(t, "string index must be an integer", func() {
+				r.eval(&AstIndexNode{Term: &AstStringNode{"abc"}, Index: &AstNumberNode{0.5}})
+			})
+		})
+	})
+
+	t.Run("Test SliceNode", func(t *testing.T) {
+		t.Parallel()
+
+		slice := func(str string, low, high interface{}) ScopeEntry {
+			n := &AstSliceNode{Term: &AstStringNode{str}}
+			if low != nil {
+				n.Low = &AstNumberNode{low}
+			}
+			if high != nil {
+				n.High = &AstNumberNode{high}
+			}
+			r := NewRuntime(nil)
+			n.Accept(r)
+			return r.stack.Pop().(ScopeEntry)
+		}
+
+		t.Run("Evaluate SliceNode", func(t *testing.T) {
+			assert.Equal(t, "él", slice("héllo", int64(1), int64(3)).Value)
+			assert.Equal(t, "ll", slice("héllo", int64(-3), int64(-1)).Value)
+			assert.Equal(t, "llo", slice("héllo", int64(2), nil).Value)
+			assert.Equal(t, "hé", slice("héllo", nil, int64(2)).Value)
+			assert.Equal(t, "héllo", slice("héllo", nil, nil).Value)
+			assert.Equal(t, "", slice("héllo", int64(5), nil).Value)
+			assert.Equal(t, "", slice("héllo", int64(2), int64(2)).Value)
+		})
+
+		t.Run("Report out of range", func(t *testing.T) {
+			assert.PanicsWithValue(t, "slice bounds 2:9 out of range for string of length 5", func() {
+				slice("héllo", int64(2), int64(9))
+			})
+			assert.PanicsWithValue(t, "slice bounds 3:1 out of range for string of length 5", func() {
+				slice("héllo", int64(3), int64(1))
+			})
+			assert.PanicsWithValue(t, "slice bounds -6: out of range for string of length 5", func() {
+				slice("héllo", int64(-6), nil)
+			})
+			assert.PanicsWithValue(t, "slice bounds :18446744073709551616 out of range for string of length 5", func() {
+				slice("héllo", nil, bigInt("18446744073709551616"))
+			})
+		})
+	})
+
 	t.Run("Test VariableNode", func(t *testing.T) {
 		t.Parallel()
 
@@ -568,4 +749,336 @@ func TestRuntime(t *testing.T) {
 			})
 		})
 	})
+
+	t.Run("Test Tracer", func(t *testing.T) {
+		t.Parallel()
+
+		t.Run("Trace statements and calls", func(t *testing.T) {
+			prog, _ := newParser("func foo {\n  return 42\n}\nx := foo()").Parse()
+			tr := &recordingTracer{}
+			r := NewRuntime(nil)
+			r.AddTracer(tr)
+			prog.Accept(r)
+
+			assert.Equal(t, []string{
+				"enter main",
+				"stmt 1 main",
+				"stmt 4 main",
+				"enter foo",
+				"stmt 2 foo",
+				"leave foo",
+				"leave main",
+			}, tr.events)
+			assert.Equal(t, 0, len(r.Frames()))
+		})
+	})
+
+	t.Run("Test Run", func(t *testing.T) {
+		t.Parallel()
+
+		run := func(ctx context.Context, src string, env *RuntimeEnv) error {
+			prog, err := newParser(src).Parse()
+			assert.Nil(t, err)
+			return NewRuntime(env).Run(ctx, prog)
+		}
+
+		t.Run("Run to completion", func(t *testing.T) {
+			env := testRuntimeEnv("")
+			err := run(context.Background(), `write("done")`, env)
+			assert.Nil(t, err)
+			assert.Equal(t, "done", env.stdout.(*bytes.Buffer).String())
+		})
+
+		t.Run("Return runtime errors", func(t *testing.T) {
+			err := run(context.Background(), "x := y", testRuntimeEnv(""))
+			assert.EqualError(t, err, "variable is not defined")
+		})
+
+		t.Run("Exit with a status", func(t *testing.T) {
+			env := testRuntimeEnv("")
+			err := run(context.Background(), "write(1)\nexit(3)\nwrite(2)", env)
+			assert.Equal(t, &ExitError{3}, err)
+			assert.EqualError(t, err, "exit status 3")
+			assert.Equal(t, "1", env.stdout.(*bytes.Buffer).String())
+		})
+
+		t.Run("Unwind calls on exit", func(t *testing.T) {
+			prog, err := newParser("func f n {\n  if n = 0 { exit() }\n  f(n - 1)\n}\nf(1)").Parse()
+			assert.Nil(t, err)
+			tr := &recordingTracer{}
+			r := NewRuntime(testRuntimeEnv(""))
+			r.AddTracer(tr)
+			assert.Equal(t, &ExitError{0}, r.Run(context.Background(), prog))
+			assert.Equal(t, 0, len(r.Frames()))
+			assert.Equal(t, 0, r.scopeStack.Size())
+			assert.Equal(t, []string{"leave exit", "leave f", "leave f", "leave main"},
+				tr.events[len(tr.events)-4:])
+		})
+
+		t.Run("Unwind after errors", func(t *testing.T) {
+			prog, err := newParser("func f n {\n  x := n + y\n}\nf(1)").Parse()
+			assert.Nil(t, err)
+			r := NewRuntime(testRuntimeEnv(""))
+			for i := 0; i < 2; i++ {
+				assert.EqualError(t, r.Run(context.Background(), prog), "variable is not defined")
+				assert.Equal(t, Pos{2, 3}, r.StopPos())
+				assert.Equal(t, 0, len(r.Frames()))
+				assert.Equal(t, 0, r.scopeStack.Size())
+				assert.Equal(t, 0, r.stack.Size())
+			}
+
+			env := testRuntimeEnv("")
+			env.maxDepth = 5
+			r = NewRuntime(env)
+			prog, _ = newParser("func f { f() }\nf()").Parse()
+			assert.Equal(t, ErrDepthLimit, r.Run(context.Background(), prog))
+			assert.Equal(t, 0, len(r.Frames()))
+			assert.Equal(t, 0, r.scopeStack.Size())
+		})
+
+		t.Run("Panic on Go runtime errors", func(t *testing.T) {
+			prog, _ := newParser("x := 1").Parse()
+			r := NewRuntime(testRuntimeEnv(""))
+			r.AddTracer(brokenTracer{})
+			defer func() {
+				_, ok := recover().(runtime.Error)
+				assert.True(t, ok)
+			}()
+			r.Run(context.Background(), prog)
+			t.Error("Run did not panic")
+		})
+
+		t.Run("Stop loop when canceled", func(t *testing.T) {
+			ctx, cancel := context.WithCancel(context.Background())
+			cancel()
+			err := run(ctx, "x := 1\nwhile true { }", testRuntimeEnv(""))
+			assert.True(t, errors.Is(err, context.Canceled))
+			assert.Equal(t, &InterruptError{Pos{2, 1}, context.Canceled}, err)
+			assert.EqualError(t, err, "2:1: context canceled")
+		})
+
+		t.Run("Stop recursion at deadline", func(t *testing.T) {
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+			defer cancel()
+			err := run(ctx, "func f { f() }\nf()", testRuntimeEnv(""))
+			assert.True(t, errors.Is(err, context.DeadlineExceeded))
+			assert.Equal(t, 1, err.(*InterruptError).Pos.Line)
+		})
+
+		t.Run("Interrupt read", func(t *testing.T) {
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+			defer cancel()
+			pr, pw := io.Pipe()
+			defer pw.Close()
+			env := testRuntimeEnv("")
+			env.stdin = pr
+			err := run(ctx, "x := 1\n  s := read()", env)
+			assert.Equal(t, &InterruptError{Pos{2, 8}, context.DeadlineExceeded}, err)
+		})
+	})
+
+	t.Run("Test decimals", func(t *testing.T) {
+		t.Parallel()
+
+		run := func(src string, env *RuntimeEnv) string {
+			prog, err := newParser(src).Parse()
+			assert.Nil(t, err)
+			prog.Accept(NewRuntime(env))
+			return env.stdout.(*bytes.Buffer).String()
+		}
+
+		t.Run("Divide with environment's scale and rounding", func(t *testing.T) {
+			env := testRuntimeEnv("")
+			assert.Equal(t, "0.33 0.12", run("write(1.00d / 3, \" \", 0.25d / 2)", env))
+
+			env = testRuntimeEnv("")
+			assert.Equal(t, "0.33 2.50 0.333", run("write(1d / 3, \" \", 10d / 4d, \" \", 1.000d / 3)", env))
+
+			env = testRuntimeEnv("")
+			env.decScale = 4
+			env.decRounding = RoundUp
+			assert.Equal(t, "0.3334 0.1250", run("write(1.00d / 3, \" \", 0.25d / 2)", env))
+
+			env = testRuntimeEnv("")
+			src := `decimal_scale(4, "up")
+write(1.00d / 3, " ", 0.25d / 2, " ")
+decimal_scale(0)
+write(2d / 3, " ", format("%.1f", 0.25d))`
+			assert.Equal(t, "0.3334 0.1250 0.67 0.3", run(src, env))
+		})
+
+		t.Run("Refuse bad scales and rounding modes", func(t *testing.T) {
+			assert.PanicsWithValue(t, "decimal_scale expects a scale and an optional rounding mode", func() {
+				run("decimal_scale()", testRuntimeEnv(""))
+			})
+			assert.PanicsWithValue(t, "decimal_scale expects a scale and an optional rounding mode", func() {
+				run("decimal_scale(-1)", testRuntimeEnv(""))
+			})
+			assert.PanicsWithValue(t, "decimal_scale expects a scale and an optional rounding mode", func() {
+				run("decimal_scale(2, 1)", testRuntimeEnv(""))
+			})
+			assert.PanicsWithValue(t, `decimal_scale: unknown rounding mode "nearest"`, func() {
+				run(`decimal_scale(2, "nearest")`, testRuntimeEnv(""))
+			})
+			env := testRuntimeEnv("")
+			env.maxSize = 100
+			assert.PanicsWithValue(t, ErrSizeLimit, func() {
+				run("decimal_scale(1000000)", env)
+			})
+		})
+
+		t.Run("Refuse floats", func(t *testing.T) {
+			assert.PanicsWithValue(t, "operation not permitted with type", func() {
+				run("x := 1.5d + 0.5", testRuntimeEnv(""))
+			})
+			assert.PanicsWithValue(t, "number cannot be represented as a decimal", func() {
+				run("x := (1 / 0):dec", testRuntimeEnv(""))
+			})
+		})
+	})
+
+	t.Run("Test containers", func(t *testing.T) {
+		t.Parallel()
+
+		run := func(src string) string {
+			prog, err := newParser(src).Parse()
+			assert.Nil(t, err)
+			env := testRuntimeEnv("")
+			prog.Accept(NewRuntime(env))
+			return env.stdout.(*bytes.Buffer).String()
+		}
+
+		t.Run("Index and slice lists and maps", func(t *testing.T) {
+			src := `l := split("a,b,c", ",") m := json_decode("{\"k\": [1, 2]}")
+				write(l[0], l[-1], m["k"][1], " ", l[1:], l[:0])`
+			assert.Equal(t, `ac2 ["b","c"][]`, run(src))
+		})
+
+		t.Run("Report bad indexes", func(t *testing.T) {
+			assert.PanicsWithValue(t, "index 3 out of range for list of length 3", func() {
+				run(`x := split("a,b,c", ",")[3]`)
+			})
+			assert.PanicsWithValue(t, "slice bounds 2:1 out of range for list of length 3", func() {
+				run(`x := split("a,b,c", ",")[2:1]`)
+			})
+			assert.PanicsWithValue(t, "list index must be an integer", func() {
+				run(`x := list(1)["0"]`)
+			})
+			assert.PanicsWithValue(t, `key "b" not found in map`, func() {
+				run(`x := map("a", 1)["b"]`)
+			})
+			assert.PanicsWithValue(t, "map key must be a string", func() {
+				run(`x := map("a", 1)[0]`)
+			})
+			assert.PanicsWithValue(t, "only strings and lists can be sliced", func() {
+				run(`x := map("a", 1)[0:]`)
+			})
+		})
+
+		t.Run("Cast containers", func(t *testing.T) {
+			assert.Equal(t, `[1,"a",null] {"k":2.5}`,
+				run(`write(list(1, "a", null()):str, " ", "${map("k", 2.5d)}")`))
+			assert.PanicsWithValue(t, "cannot cast list to num", func() {
+				run(`x := list():num`)
+			})
+			assert.PanicsWithValue(t, "cannot cast null to bool", func() {
+				run(`x := null():bool`)
+			})
+		})
+
+		t.Run("Compare containers and null", func(t *testing.T) {
+			src := `write(list(1, "a") = split("1,a", ","), " ", list(1) = list(1.0), " ",
+				map("a", 1) ~= map("a", 2), " ", 1 = null(), " ", null() = null(), " ", map() ~= null())`
+			assert.Equal(t, "false true true false true true", run(src))
+		})
+	})
+
+	t.Run("Test limits", func(t *testing.T) {
+		t.Parallel()
+
+		run := func(src string, env *RuntimeEnv) {
+			prog, err := newParser(src).Parse()
+			assert.Nil(t, err)
+			prog.Accept(NewRuntime(env))
+		}
+
+		t.Run("Stop after too many nodes", func(t *testing.T) {
+			env := testRuntimeEnv("")
+			env.maxNodes = 100
+			assert.PanicsWithValue(t, ErrNodeLimit, func() {
+				run("while true { }", env)
+			})
+			assert.NotPanics(t, func() {
+				run("i := 0 while i < 5 { i := i + 1 }", env)
+			})
+		})
+
+		t.Run("Stop calls nested too deeply", func(t *testing.T) {
+			env := testRuntimeEnv("")
+			env.maxDepth = 10
+			assert.PanicsWithValue(t, ErrDepthLimit, func() {
+				run("func f { f() } f()", env)
+			})
+			assert.NotPanics(t, func() {
+				run("func f n { if n > 0 { f(n - 1) } } f(9)", env)
+			})
+		})
+
+		t.Run("Stop strings growing too long", func(t *testing.T) {
+			env := testRuntimeEnv("")
+			env.maxSize = 8
+			assert.PanicsWithValue(t, ErrSizeLimit, func() {
+				run(`s := "ab" while true { s := s + s }`, env)
+			})
+			assert.NotPanics(t, func() {
+				run(`s := "abcd" + "efgh"`, env)
+			})
+		})
+
+		t.Run("Stop reading too much", func(t *testing.T) {
+			env := testRuntimeEnv("abcdefghi\n")
+			env.maxSize = 8
+			assert.PanicsWithValue(t, ErrSizeLimit, func() {
+				run("s := read()", env)
+			})
+			env = testRuntimeEnv("abcdefgh\n")
+			env.maxSize = 8
+			assert.NotPanics(t, func() {
+				run("s := read()", env)
+			})
+		})
+	})
+}
+
+// recordingTracer implements the Tracer interface to log events.
+type recordingTracer struct {
+	events []string
+}
+
+func (tr *recordingTracer) Stmt(r *Runtime, n AstStmtNode) {
+	f := r.Frames()[len(r.Frames())-1]
+	tr.events = append(tr.events,
+		fmt.Sprintf("stmt %d %s", n.Position().Line, f.Name))
+}
+
+func (tr *recordingTracer) Enter(r *Runtime, f *Frame) {
+	tr.events = append(tr.events, "enter "+f.Name)
 }
+
+func (tr *recordingTracer) Leave(r *Runtime, f *Frame) {
+	tr.events = append(tr.events, "leave "+f.Name)
+}
+
+// brokenTracer implements the Tracer interface with a bug, dereferencing
+// nil before each statement.
+type brokenTracer struct {
+	frame *Frame
+}
+
+func (tr brokenTracer) Stmt(r *Runtime, n AstStmtNode) {
+	_ = tr.frame.Name
+}
+
+func (tr brokenTracer) Enter(r *Runtime, f *Frame) {}
+
+func (tr brokenTracer) Leave(r *Runtime, f *Frame) {}
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Capability is a set of permissions a builtin needs to do its work. A
+// program may only call the builtins whose capabilities its RuntimeEnv
+// grants.
+type Capability uint
+
+const (
+	CapStdio Capability = 1 << iota
+	CapFSRead
+	CapFSWrite
+	CapEnv
+	CapProcess
+	CapClock
+	CapRandom
+
+	// CapDefault is granted to programs run from the command line.
+	CapDefault = CapStdio | CapProcess | CapClock | CapRandom
+	// CapAll grants every capability.
+	CapAll = CapStdio | CapFSRead | CapFSWrite | CapEnv | CapProcess |
+		CapClock | CapRandom
+)
+
+var capNames = []string{"stdio", "read", "write", "env", "process", "clock",
+	"random"}
+
+func (c Capability) String() string {
+	var names []string
+	for i, name := range capNames {
+		if c&(1<<uint(i)) != 0 {
+			names = append(names, name)
+		}
+	}
+	if len(names) == 0 {
+		return "none"
+	}
+	return strings.Join(names, "|")
+}
+
+// PermissionError is raised when a program calls a builtin that needs a
+// capability its environment does not grant, or accesses a path outside
+// those it may read or write.
+type PermissionError struct {
+	Builtin string
+	Cap     Capability
+	Path    string
+}
+
+func (e *PermissionError) Error() string {
+	if e.Path != "" {
+		return fmt.Sprintf("%s: %s access to %s not permitted", e.Builtin,
+			e.Cap, e.Path)
+	}
+	return fmt.Sprintf("%s: %s capability not permitted", e.Builtin, e.Cap)
+}
+
+// require panics with a PermissionError unless env grants every capability
+// in caps to the named builtin.
+func (env *RuntimeEnv) require(name string, caps Capability) {
+	var granted Capability
+	if env != nil {
+		granted = env.caps
+	}
+	if missing := caps &^ granted; missing != 0 {
+		panic(&PermissionError{Builtin: name, Cap: missing})
+	}
+}
+
+// realPath returns the absolute form of path with its symbolic links
+// followed. A path that does not exist yet, such as a file about to be
+// written, is resolved through its parent directory, and a link to such a
+// path through the link's target.
+func realPath(path string) (string, error) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return "", err
+	}
+	real, err := filepath.EvalSymlinks(abs)
+	if err == nil || !os.IsNotExist(err) {
+		return real, err
+	}
+	if target, err := os.Readlink(abs); err == nil {
+		if !filepath.IsAbs(target) {
+			target = filepath.Join(filepath.Dir(abs), target)
+		}
+		return realPath(target)
+	}
+	dir, err := realPath(filepath.Dir(abs))
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, filepath.Base(abs)), nil
+}
+
+// checkPath panics with a PermissionError unless path lies within one of
+// the directories env allows the named builtin to read (CapFSRead) or write
+// (CapFSWrite). Symbolic links are followed, so a link cannot lead outside
+// the directories. An empty list of directories allows any path.
+func (env *RuntimeEnv) checkPath(name string, c Capability, path string) {
+	dirs := env.readPaths
+	if c == CapFSWrite {
+		dirs = env.writePaths
+	}
+	if len(dirs) == 0 {
+		return
+	}
+	abs, err := realPath(path)
+	if err != nil {
+		panic(err)
+	}
+	for _, dir := range dirs {
+		dir, err := realPath(dir)
+		if err != nil {
+			panic(err)
+		}
+		rel, err := filepath.Rel(dir, abs)
+		if err == nil && rel != ".." &&
+			!strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			return
+		}
+	}
+	panic(&PermissionError{Builtin: name, Cap: c, Path: path})
+}
@@ -0,0 +1,151 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSandbox(t *testing.T) {
+	t.Parallel()
+
+	dir, err := ioutil.TempDir("", "kiwi")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	file := filepath.Join(dir, "data.txt")
+	if err := ioutil.WriteFile(file, []byte("data"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	run := func(src string, env *RuntimeEnv) error {
+		prog, err := newParser(src).Parse()
+		assert.Nil(t, err)
+		return NewRuntime(env).Run(context.Background(), prog)
+	}
+
+	t.Run("Name capabilities", func(t *testing.T) {
+		assert.Equal(t, "none", Capability(0).String())
+		assert.Equal(t, "read", CapFSRead.String())
+		assert.Equal(t, "stdio|process|clock|random", CapDefault.String())
+	})
+
+	t.Run("Deny builtins without capabilities", func(t *testing.T) {
+		env := testRuntimeEnv("")
+		env.caps = 0
+		err := run(`write("hi")`, env)
+		assert.Equal(t, &PermissionError{Builtin: "write", Cap: CapStdio}, err)
+		assert.EqualError(t, err, "write: stdio capability not permitted")
+		assert.Equal(t, "", env.stdout.(*bytes.Buffer).String())
+	})
+
+	t.Run("Allow builtins needing no capabilities", func(t *testing.T) {
+		env := testRuntimeEnv("")
+		env.caps = 0
+		assert.Nil(t, run(`n := strlen("hi")`, env))
+	})
+
+	t.Run("Exit the process", func(t *testing.T) {
+		env := testRuntimeEnv("")
+		assert.Equal(t, &ExitError{2}, run(`exit(2)`, env))
+
+		env.caps &^= CapProcess
+		err := run(`exit(2)`, env)
+		assert.EqualError(t, err, "exit: process capability not permitted")
+	})
+
+	t.Run("Read files", func(t *testing.T) {
+		env := testRuntimeEnv("")
+		err := run(`write(readfile("`+file+`"))`, env)
+		assert.EqualError(t, err, "readfile: read capability not permitted")
+
+		env.caps |= CapFSRead
+		assert.Nil(t, run(`write(readfile("`+file+`"))`, env))
+		assert.Equal(t, "data", env.stdout.(*bytes.Buffer).String())
+	})
+
+	t.Run("Read files in allowed directories", func(t *testing.T) {
+		env := testRuntimeEnv("")
+		env.caps |= CapFSRead
+		env.readPaths = []string{dir}
+		assert.Nil(t, run(`s := readfile("`+file+`")`, env))
+
+		env.readPaths = []string{filepath.Join(dir, "sub")}
+		err := run(`s := readfile("`+file+`")`, env)
+		assert.Equal(t, &PermissionError{"readfile", CapFSRead, file}, err)
+
+		env.readPaths = []string{dir + "x"}
+		assert.NotNil(t, run(`s := readfile("`+file+`x")`, env))
+	})
+
+	t.Run("Write files in allowed directories", func(t *testing.T) {
+		out := filepath.Join(dir, "out.txt")
+		env := testRuntimeEnv("")
+		env.caps |= CapFSRead
+		err := run(`writefile("`+out+`", "written")`, env)
+		assert.EqualError(t, err, "writefile: write capability not permitted")
+
+		env.caps |= CapFSWrite
+		env.writePaths = []string{filepath.Join(dir, "sub")}
+		err = run(`writefile("`+out+`", "written")`, env)
+		assert.EqualError(t, err, "writefile: write access to "+out+" not permitted")
+
+		env.writePaths = []string{dir}
+		assert.Nil(t, run(`writefile("`+out+`", "written")`, env))
+		b, _ := ioutil.ReadFile(out)
+		assert.Equal(t, "written", string(b))
+	})
+
+	t.Run("Deny links out of allowed directories", func(t *testing.T) {
+		outside, err := ioutil.TempDir("", "kiwi")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer os.RemoveAll(outside)
+		secret := filepath.Join(outside, "secret.txt")
+		if err := ioutil.WriteFile(secret, []byte("secret"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		allowed := filepath.Join(dir, "links")
+		os.Mkdir(allowed, 0755)
+		for name, target := range map[string]string{
+			"secret.txt": secret,
+			"out":        outside,
+			"new.txt":    filepath.Join(outside, "new.txt"),
+			"data.txt":   file,
+		} {
+			if err := os.Symlink(target, filepath.Join(allowed, name)); err != nil {
+				t.Skip("symbolic links are not supported: ", err)
+			}
+		}
+
+		env := testRuntimeEnv("")
+		env.caps |= CapFSRead | CapFSWrite
+		env.readPaths = []string{allowed}
+		env.writePaths = []string{allowed}
+
+		link := filepath.Join(allowed, "secret.txt")
+		err = run(`s := readfile("`+link+`")`, env)
+		assert.Equal(t, &PermissionError{"readfile", CapFSRead, link}, err)
+
+		for _, link := range []string{
+			filepath.Join(allowed, "out", "made.txt"),
+			filepath.Join(allowed, "new.txt"),
+		} {
+			err = run(`writefile("`+link+`", "x")`, env)
+			assert.Equal(t, &PermissionError{"writefile", CapFSWrite, link}, err)
+		}
+		_, err = os.Stat(filepath.Join(outside, "new.txt"))
+		assert.True(t, os.IsNotExist(err))
+
+		// a link to a file in an allowed directory may be followed
+		env.readPaths = []string{allowed, dir}
+		assert.Nil(t, run(`s := readfile("`+filepath.Join(allowed, "data.txt")+`")`, env))
+	})
+}
@@ -11,21 +11,52 @@ import (
 // convenient representation of EOF
 const eof = rune(0)
 
+// Pos is a location in the source, counted from line 1 column 1.
+type Pos struct {
+	Line int
+	Col  int
+}
+
+// Position returns the position itself so nodes that embed a Pos can
+// report where they begin.
+func (p Pos) Position() Pos {
+	return p
+}
+
 // Scanner lexes a stream of characters (runes) into tokens and lexemes.
 type Scanner struct {
-	r *bufio.Reader
+	r       *bufio.Reader
+	pos     Pos
+	prev    Pos
+	tokPos  Pos
+	interps []interpolation
+	started bool
+}
+
+// interpolation records the string an embedded expression appears in, so the
+// string can resume at the brace that closes the expression.
+type interpolation struct {
+	delim  int // number of quotes that close the string
+	braces int // braces left open within the expression
 }
 
 // NewScanner returns a new scanner that reads from r.
 func NewScanner(r io.Reader) *Scanner {
-	return &Scanner{bufio.NewReader(r)}
+	return &Scanner{r: bufio.NewReader(r), pos: Pos{1, 1}}
 }
 
 // read manages the scanner's buffer and returns runes from it.
 func (s *Scanner) read() rune {
+	s.prev = s.pos
 	ch, _, err := s.r.ReadRune()
 	if err != nil {
-		ch = eof
+		return eof
+	}
+	if ch == '\n' {
+		s.pos.Line++
+		s.pos.Col = 1
+	} else {
+		s.pos.Col++
 	}
 	return ch
 }
@@ -33,12 +64,28 @@ func (s *Scanner) read() rune {
 // unread pushes the most recently read rune back to the stream.
 func (s *Scanner) unread() {
 	s.r.UnreadRune()
+	s.pos = s.prev
+}
+
+// Pos returns the position of the first rune of the most recently scanned
+// lexeme.
+func (s *Scanner) Pos() Pos {
+	return s.tokPos
 }
 
 // Scan consumes a lexeme from the reader's stream and returns its Token and
-// string values.
+// string values. A "#!" line at the very start of the stream is scanned as
+// a comment.
 func (s *Scanner) Scan() (Token, string) {
+	if !s.started {
+		s.started = true
+		if b, _ := s.r.Peek(2); string(b) == "#!" {
+			s.tokPos = s.pos
+			return s.scanShebang()
+		}
+	}
 	s.skipWhitespace()
+	s.tokPos = s.pos
 	ch := s.read()
 
 	switch ch {
@@ -49,6 +96,11 @@ func (s *Scanner) Scan() (Token, string) {
 	case '-':
 		return TkSubtract, "-"
 	case '*':
+		ch = s.read()
+		if ch == '*' {
+			return TkPower, "**"
+		}
+		s.unread()
 		return TkMultiply, "*"
 	case '/':
 		ch = s.read()
@@ -111,21 +163,49 @@ func (s *Scanner) Scan() (Token, string) {
 	case ')':
 		return TkRParen, ")"
 	case '{':
+		if n := len(s.interps); n > 0 {
+			s.interps[n-1].braces++
+		}
 		return TkLBrace, "{"
 	case '}':
+		if n := len(s.interps); n > 0 {
+			if s.interps[n-1].braces == 0 {
+				delim := s.interps[n-1].delim
+				s.interps = s.interps[:n-1]
+				var buf bytes.Buffer
+				buf.WriteRune('}')
+				return s.scanSegment(&buf, delim, false, TkInterpEnd, TkInterpMid)
+			}
+			s.interps[n-1].braces--
+		}
 		return TkRBrace, "}"
+	case '[':
+		return TkLBracket, "["
+	case ']':
+		return TkRBracket, "]"
 	case ',':
 		return TkComma, ","
+	case '.':
+		// a point begins a number when a digit follows
+		if b, err := s.r.Peek(1); err == nil && b[0] >= '0' && b[0] <= '9' {
+			tok, val := s.scanNumber()
+			return tok, "." + val
+		}
+		return TkUnknown, "."
 	case '"':
-		return s.scanString()
+		return s.scanString(false)
 	case '`':
-		s.unread()
-		return s.scanIdent()
+		return s.scanIdent(ch)
+	case 'r':
+		// a quote after r begins a raw string
+		if b, err := s.r.Peek(1); err == nil && b[0] == '"' {
+			s.read()
+			return s.scanString(true)
+		}
 	}
 
 	if unicode.IsLetter(ch) {
-		s.unread()
-		return s.scanIdent()
+		return s.scanIdent(ch)
 	}
 	if unicode.IsDigit(ch) {
 		s.unread()
@@ -146,51 +226,73 @@ func (s *Scanner) skipWhitespace() {
 	}
 }
 
-// scanString consumes a string lexeme and returns its token and value. Escape
-// sequences in the string are evaluated and replaced.
-func (s *Scanner) scanString() (Token, string) {
+// scanString consumes a string lexeme, whose opening quote has been read,
+// and returns its token and value. The value is the lexeme as written,
+// including its quotes and any "r" prefix marking a raw string, and is
+// decoded by the parser. A string opened with three quotes ends with three
+// quotes and may span lines.
+func (s *Scanner) scanString(raw bool) (Token, string) {
 	var buf bytes.Buffer
+	if raw {
+		buf.WriteRune('r')
+	}
+	delim := 1
+	if b, err := s.r.Peek(2); err == nil && string(b) == `""` {
+		s.read()
+		s.read()
+		delim = 3
+	}
+	buf.WriteString(strings.Repeat(`"`, delim))
+	return s.scanSegment(&buf, delim, raw, TkString, TkInterpStart)
+}
+
+// scanSegment consumes the rest of a string segment into buf and returns its
+// token and value. The token is closed if the segment ends the string, or
+// open if it ends at the "${" that begins an embedded expression. Raw strings
+// don't embed expressions.
+func (s *Scanner) scanSegment(buf *bytes.Buffer, delim int, raw bool, closed, open Token) (Token, string) {
+	quotes := 0
 	for {
-		if ch := s.read(); ch != '"' {
-			// must have a closing quote
-			if ch == eof {
-				return TkUnknown, buf.String()
+		ch := s.read()
+		// must have a closing quote
+		if ch == eof {
+			return TkUnknown, buf.String()
+		}
+		buf.WriteRune(ch)
+		if ch == '"' {
+			if quotes++; quotes == delim {
+				return closed, buf.String()
 			}
-			if ch == '\\' {
-				switch s.read() {
-				case '\\':
-					ch = '\\'
-					break
-				case 'r':
-					ch = '\r'
-					break
-				case 'n':
-					ch = '\n'
-					break
-				case 't':
-					ch = '\t'
-					break
-				case '"':
-					ch = '"'
-					break
-				default:
-					s.unread()
-				}
+			continue
+		}
+		quotes = 0
+		if raw {
+			continue
+		}
+		// an escaped character never closes the string
+		if ch == '\\' {
+			if ch = s.read(); ch == eof {
+				return TkUnknown, buf.String()
 			}
 			buf.WriteRune(ch)
-		} else {
-			break
+			continue
+		}
+		if ch == '$' {
+			if b, err := s.r.Peek(1); err == nil && b[0] == '{' {
+				buf.WriteRune(s.read())
+				s.interps = append(s.interps, interpolation{delim: delim})
+				return open, buf.String()
+			}
 		}
 	}
-	return TkString, buf.String()
 }
 
-// scanIdent consumes an identifier lexeme and returns its token and value. An
-// identifier will be recognized as a keyword if it matches the list of Kiwi
-// keywords and is not escaped.
-func (s *Scanner) scanIdent() (Token, string) {
+// scanIdent consumes an identifier lexeme, whose first rune ch has been read,
+// and returns its token and value. An identifier will be recognized as a
+// keyword if it matches the list of Kiwi keywords and is not escaped.
+func (s *Scanner) scanIdent(ch rune) (Token, string) {
 	var buf bytes.Buffer
-	buf.WriteRune(s.read())
+	buf.WriteRune(ch)
 
 	for {
 		if ch := s.read(); unicode.IsLetter(ch) ||
@@ -227,33 +329,32 @@ func (s *Scanner) scanIdent() (Token, string) {
 }
 
 // scanNumber consumes a numeric lexeme and returns its token and value. The
-// numeric value may be an integer or real number.
+// lexeme takes in every letter, digit, underscore and point that follows, and
+// the sign of an exponent, so a malformed literal is consumed whole and can
+// be reported by the parser. A "d" suffix marks a decimal.
 func (s *Scanner) scanNumber() (Token, string) {
-	var ch rune
 	var buf bytes.Buffer
-
-	buf.WriteRune(s.read())
 	for {
-		ch = s.read()
-		if !unicode.IsDigit(ch) {
+		ch := s.read()
+		if ch == '+' || ch == '-' {
+			str := strings.ToLower(buf.String())
+			if !strings.HasSuffix(str, "e") || strings.HasPrefix(str, "0x") {
+				s.unread()
+				break
+			}
+		} else if !unicode.IsLetter(ch) && !unicode.IsDigit(ch) && ch != '_' &&
+			ch != '.' {
+			s.unread()
 			break
 		}
 		buf.WriteRune(ch)
 	}
 
-	if ch == '.' {
-		buf.WriteRune(ch)
-		for {
-			ch = s.read()
-			if !unicode.IsDigit(ch) {
-				break
-			}
-			buf.WriteRune(ch)
-		}
+	str := buf.String()
+	if strings.HasSuffix(str, "d") && !strings.HasPrefix(strings.ToLower(str), "0x") {
+		return TkDecimal, strings.TrimSuffix(str, "d")
 	}
-	s.unread()
-
-	return TkNumber, buf.String()
+	return TkNumber, str
 }
 
 // scanLineComment consumes a full-line comment and returns its token and
@@ -272,6 +373,20 @@ func (s *Scanner) scanLineComment() (Token, string) {
 	return TkComment, buf.String()
 }
 
+// scanShebang consumes the "#!" line that may begin a script run as an
+// executable, and returns it as a comment.
+func (s *Scanner) scanShebang() (Token, string) {
+	var buf bytes.Buffer
+	for {
+		ch := s.read()
+		if ch == '\n' || ch == eof {
+			break
+		}
+		buf.WriteRune(ch)
+	}
+	return TkComment, buf.String()
+}
+
 // scanMultiComment consumes a muti-line comment and returns its token and
 // lexeme value. Nested multi-line comments are accomodated.
 func (s *Scanner) scanMultiComment() (Token, string) {
@@ -11,7 +11,7 @@ func TestScanner(t *testing.T) {
 	t.Parallel()
 
 	t.Run("Test scan simple tokens", func(t *testing.T) {
-		str := "+ - * / % := : = < <= > >= && & || | ~ ~= ( ) { } , ?"
+		str := "+ - * / % ** := : = < <= > >= && & || | ~ ~= ( ) [ ] { } , ?"
 		s := NewScanner(strings.NewReader(str))
 
 		tokens := []struct {
@@ -23,6 +23,7 @@ func TestScanner(t *testing.T) {
 			{TkMultiply, "*"},
 			{TkDivide, "/"},
 			{TkModulo, "%"},
+			{TkPower, "**"},
 			{TkAssign, ":="},
 			{TkColon, ":"},
 			{TkEqual, "="},
@@ -38,6 +39,8 @@ func TestScanner(t *testing.T) {
 			{TkNotEqual, "~="},
 			{TkLParen, "("},
 			{TkRParen, ")"},
+			{TkLBracket, "["},
+			{TkRBracket, "]"},
 			{TkLBrace, "{"},
 			{TkRBrace, "}"},
 			{TkComma, ","},
@@ -79,20 +82,59 @@ func TestScanner(t *testing.T) {
 	})
 
 	t.Run("Test scan strings", func(t *testing.T) {
-		str := `"abc"` +
-			`""` +
-			`"\\\"\r\n\t\x"` +
-			`"broken`
+		str := `"abc" ` +
+			`"" ` +
+			`"\\\"\r\n\t\x41" ` +
+			`r"C:\dir\" ` +
+			`"""say "hi" """ ` +
+			"\"\"\"\n  two\n  lines\n  \"\"\" " +
+			`rest r "broken`
 		s := NewScanner(strings.NewReader(str))
 
 		tokens := []struct {
 			token Token
 			value string
 		}{
-			{TkString, "abc"},
-			{TkString, ""},
-			{TkString, "\\\"\r\n\t\\x"},
-			{TkUnknown, "broken"},
+			{TkString, `"abc"`},
+			{TkString, `""`},
+			{TkString, `"\\\"\r\n\t\x41"`},
+			{TkString, `r"C:\dir\"`},
+			{TkString, `"""say "hi" """`},
+			{TkString, "\"\"\"\n  two\n  lines\n  \"\"\""},
+			{TkIdentifier, "rest"},
+			{TkIdentifier, "r"},
+			{TkUnknown, `"broken`},
+		}
+
+		for _, expected := range tokens {
+			actual1, actual2 := s.Scan()
+			assert.Equal(t, expected.token, actual1)
+			assert.Equal(t, expected.value, actual2)
+		}
+	})
+
+	t.Run("Test scan interpolated strings", func(t *testing.T) {
+		str := `"a${b}c${ {d} }\${e}" "x${"${y}"}" r"${z}"`
+		s := NewScanner(strings.NewReader(str))
+
+		tokens := []struct {
+			token Token
+			value string
+		}{
+			{TkInterpStart, `"a${`},
+			{TkIdentifier, "b"},
+			{TkInterpMid, `}c${`},
+			{TkLBrace, "{"},
+			{TkIdentifier, "d"},
+			{TkRBrace, "}"},
+			{TkInterpEnd, `}\${e}"`},
+			{TkInterpStart, `"x${`},
+			{TkInterpStart, `"${`},
+			{TkIdentifier, "y"},
+			{TkInterpEnd, `}"`},
+			{TkInterpEnd, `}"`},
+			{TkString, `r"${z}"`},
+			{TkEOF, ""},
 		}
 
 		for _, expected := range tokens {
@@ -142,8 +184,43 @@ func TestScanner(t *testing.T) {
 		}
 	})
 
+	t.Run("Test scan shebang line", func(t *testing.T) {
+		s := NewScanner(strings.NewReader("#!/usr/bin/env kiwi -A\nx := 1"))
+		tokens := []struct {
+			token Token
+			value string
+			pos   Pos
+		}{
+			{TkComment, "#!/usr/bin/env kiwi -A", Pos{1, 1}},
+			{TkIdentifier, "x", Pos{2, 1}},
+			{TkAssign, ":=", Pos{2, 3}},
+		}
+		for _, expected := range tokens {
+			actual1, actual2 := s.Scan()
+			assert.Equal(t, expected.token, actual1)
+			assert.Equal(t, expected.value, actual2)
+			assert.Equal(t, expected.pos, s.Pos())
+		}
+
+		s = NewScanner(strings.NewReader("#!kiwi"))
+		actual1, _ := s.Scan()
+		assert.Equal(t, TkComment, actual1)
+		actual1, _ = s.Scan()
+		assert.Equal(t, TkEOF, actual1)
+
+		// only the first line of the input may be a shebang
+		for _, str := range []string{" #!kiwi", "\n#!kiwi", "x #!kiwi", "#kiwi"} {
+			s = NewScanner(strings.NewReader(str))
+			tok := TkComment
+			for tok == TkComment || tok == TkIdentifier {
+				tok, _ = s.Scan()
+			}
+			assert.Equal(t, TkUnknown, tok, str)
+		}
+	})
+
 	t.Run("Test scan numbers", func(t *testing.T) {
-		str := "123 0.123 1."
+		str := "123 0.123 1. 19.99d 5d .5 1e-9 2E+3-1 0xFFd 0o755 0b1010 1_000 0x 1e .x"
 		s := NewScanner(strings.NewReader(str))
 
 		tokens := []struct {
@@ -153,6 +230,21 @@ func TestScanner(t *testing.T) {
 			{TkNumber, "123"},
 			{TkNumber, "0.123"},
 			{TkNumber, "1."},
+			{TkDecimal, "19.99"},
+			{TkDecimal, "5"},
+			{TkNumber, ".5"},
+			{TkNumber, "1e-9"},
+			{TkNumber, "2E+3"},
+			{TkSubtract, "-"},
+			{TkNumber, "1"},
+			{TkNumber, "0xFFd"},
+			{TkNumber, "0o755"},
+			{TkNumber, "0b1010"},
+			{TkNumber, "1_000"},
+			{TkNumber, "0x"},
+			{TkNumber, "1e"},
+			{TkUnknown, "."},
+			{TkIdentifier, "x"},
 		}
 
 		for _, expected := range tokens {
@@ -161,4 +253,20 @@ func TestScanner(t *testing.T) {
 			assert.Equal(t, expected.value, actual2)
 		}
 	})
+
+	t.Run("Test scan positions", func(t *testing.T) {
+		str := "foo := 42\n  bar(\"a\nb\")"
+		s := NewScanner(strings.NewReader(str))
+
+		positions := []Pos{
+			{1, 1}, {1, 5}, {1, 8},
+			{2, 3}, {2, 6}, {2, 7},
+			{3, 3}, {3, 4},
+		}
+
+		for _, expected := range positions {
+			s.Scan()
+			assert.Equal(t, expected, s.Pos())
+		}
+	})
 }
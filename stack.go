@@ -23,10 +23,9 @@ func (s Stack) Peek() interface{} {
 
 // Push removes and returns the entry from the top of the stack.
 func (s *Stack) Pop() interface{} {
-	defer func() {
-		(*s) = (*s)[:s.Size()-1]
-	}()
-	return s.Peek()
+	e := s.Peek()
+	(*s) = (*s)[:s.Size()-1]
+	return e
 }
 
 // Size returns the number of entries on the stack.
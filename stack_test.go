@@ -33,7 +33,7 @@ func TestStack(t *testing.T) {
 
 	t.Run("Pop empty stack", func(t *testing.T) {
 		s := NewStack()
-		assert.Panics(t, func() {
+		assert.PanicsWithValue(t, "Attempt to access an empty stack", func() {
 			s.Pop()
 		})
 	})
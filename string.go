@@ -0,0 +1,151 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// parseStrLit converts the string literal lit, written with its quotes, to
+// the string it denotes. Escape sequences are replaced unless the literal is
+// raw, and the lines of a triple-quoted literal lose their common
+// indentation.
+func parseStrLit(lit string) (string, error) {
+	raw := strings.HasPrefix(lit, "r")
+	if raw {
+		lit = lit[1:]
+	}
+	var body string
+	if strings.HasPrefix(lit, `"""`) {
+		body = dedent(lit[3 : len(lit)-3])
+	} else {
+		body = lit[1 : len(lit)-1]
+	}
+	if raw {
+		return body, nil
+	}
+	return unescape(body)
+}
+
+// parseInterpLit converts the segments of an interpolated string literal
+// to the strings that surround its embedded expressions. The segments are
+// written as scanned, from the opening quote to the first "${", between each
+// "}" and the next "${", and from the last "}" to the closing quote.
+func parseInterpLit(segs []string) ([]string, error) {
+	delim := 1
+	if strings.HasPrefix(segs[0], `"""`) {
+		delim = 3
+	}
+	strs := make([]string, len(segs))
+	for i, seg := range segs {
+		start, end := 1, len(seg)-2
+		if i == 0 {
+			start = delim
+		}
+		if i == len(segs)-1 {
+			end = len(seg) - delim
+		}
+		strs[i] = seg[start:end]
+	}
+	if delim == 3 {
+		// the scanner never includes NUL in a lexeme, so it can mark
+		// where the expressions were while the indentation is removed
+		strs = strings.Split(dedent(strings.Join(strs, "\x00")), "\x00")
+	}
+	for i := range strs {
+		str, err := unescape(strs[i])
+		if err != nil {
+			return nil, err
+		}
+		strs[i] = str
+	}
+	return strs, nil
+}
+
+// dedent removes the leading whitespace common to the lines of s that
+// aren't blank. A blank first or last line, holding only the opening or
+// closing quotes, is removed too.
+func dedent(s string) string {
+	lines := strings.Split(s, "\n")
+	if len(lines) > 1 && isBlank(lines[0]) {
+		lines = lines[1:]
+	}
+	if len(lines) > 1 && isBlank(lines[len(lines)-1]) {
+		lines = lines[:len(lines)-1]
+	}
+
+	prefix, found := "", false
+	for _, line := range lines {
+		if isBlank(line) {
+			continue
+		}
+		indent := line[:len(line)-len(strings.TrimLeft(line, " \t"))]
+		if !found {
+			prefix, found = indent, true
+			continue
+		}
+		for !strings.HasPrefix(indent, prefix) {
+			prefix = prefix[:len(prefix)-1]
+		}
+	}
+
+	for i, line := range lines {
+		if isBlank(line) {
+			lines[i] = ""
+		} else {
+			lines[i] = line[len(prefix):]
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+func isBlank(s string) bool {
+	return strings.TrimLeft(s, " \t\r") == ""
+}
+
+// unescape replaces the escape sequences in s with the characters they
+// denote.
+func unescape(s string) (string, error) {
+	var buf strings.Builder
+	for i := 0; i < len(s); {
+		if s[i] != '\\' {
+			buf.WriteByte(s[i])
+			i++
+			continue
+		}
+		if i+1 == len(s) {
+			return "", errors.New("escape sequence not terminated")
+		}
+		ch := s[i+1]
+		i += 2
+		switch ch {
+		case '\\', '"', '$':
+			buf.WriteByte(ch)
+		case '0':
+			buf.WriteByte(0)
+		case 'n':
+			buf.WriteByte('\n')
+		case 'r':
+			buf.WriteByte('\r')
+		case 't':
+			buf.WriteByte('\t')
+		case 'x', 'u', 'U':
+			n := map[byte]int{'x': 2, 'u': 4, 'U': 8}[ch]
+			if i+n > len(s) || strings.Trim(s[i:i+n], "0123456789abcdefABCDEF") != "" {
+				return "", fmt.Errorf("\\%c escape needs %d hexadecimal digits", ch, n)
+			}
+			v, _ := strconv.ParseUint(s[i:i+n], 16, 32)
+			if !utf8.ValidRune(rune(v)) {
+				return "", fmt.Errorf("escape \\%s is not a valid code point", s[i-1:i+n])
+			}
+			buf.WriteRune(rune(v))
+			i += n
+		default:
+			r, _ := utf8.DecodeRuneInString(s[i-1:])
+			return "", fmt.Errorf("unknown escape sequence \\%c", r)
+		}
+	}
+	return buf.String(), nil
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStrings(t *testing.T) {
+	t.Parallel()
+
+	t.Run("Parse literals", func(t *testing.T) {
+		tests := []struct {
+			lit, expected string
+		}{
+			{`"abc"`, "abc"},
+			{`""`, ""},
+			{`"\\\"\r\n\t"`, "\\\"\r\n\t"},
+			{`"a\0b"`, "a\x00b"},
+			{`"\x41\x7e"`, "A~"},
+			{`"\xe9"`, "é"},
+			{`"\u00e9\u4e16"`, "é世"},
+			{`"\U0001F600"`, "😀"},
+			{`r"C:\dir\n"`, `C:\dir\n`},
+			{`"""say "hi" """`, `say "hi" `},
+			{`r"""\d+ "quoted" """`, `\d+ "quoted" `},
+		}
+		for _, test := range tests {
+			s, err := parseStrLit(test.lit)
+			assert.Nil(t, err, test.lit)
+			assert.Equal(t, test.expected, s, test.lit)
+		}
+	})
+
+	t.Run("Strip common indentation", func(t *testing.T) {
+		s, _ := parseStrLit("\"\"\"\n    first\n      second\n\n    third\\n\n    \"\"\"")
+		assert.Equal(t, "first\n  second\n\nthird\n", s)
+
+		s, _ = parseStrLit("\"\"\"one\n\ttwo\"\"\"")
+		assert.Equal(t, "one\n\ttwo", s)
+
+		s, _ = parseStrLit("r\"\"\"\n  \\t\n  \"\"\"")
+		assert.Equal(t, `\t`, s)
+	})
+
+	t.Run("Parse interpolated literals", func(t *testing.T) {
+		strs, err := parseInterpLit([]string{`"a\t${`, `}\${x}${`, `}"`})
+		assert.Nil(t, err)
+		assert.Equal(t, []string{"a\t", "${x}", ""}, strs)
+
+		strs, _ = parseInterpLit([]string{"\"\"\"\n    x = ${", "}\n      ${", "}\n    \"\"\""})
+		assert.Equal(t, []string{"x = ", "\n  ", ""}, strs)
+	})
+
+	t.Run("Report malformed literals", func(t *testing.T) {
+		tests := []struct {
+			lit, err string
+		}{
+			{`"\q"`, `unknown escape sequence \q`},
+			{`"\é"`, `unknown escape sequence \é`},
+			{`"\x4"`, `\x escape needs 2 hexadecimal digits`},
+			{`"\u00g0"`, `\u escape needs 4 hexadecimal digits`},
+			{`"\U1F600"`, `\U escape needs 8 hexadecimal digits`},
+			{`"\ud800"`, `escape \ud800 is not a valid code point`},
+			{`"\U00110000"`, `escape \U00110000 is not a valid code point`},
+		}
+		for _, test := range tests {
+			_, err := parseStrLit(test.lit)
+			assert.EqualError(t, err, test.err, test.lit)
+		}
+	})
+}
@@ -0,0 +1,283 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+type (
+	// TestResult is the outcome of running one test function.
+	TestResult struct {
+		Name     string
+		Pos      Pos
+		Passed   bool
+		Failure  bool // an assertion failed, as opposed to a runtime error
+		Message  string
+		Output   string
+		Duration time.Duration
+	}
+
+	// TestSuite holds the results of the test functions in one file.
+	TestSuite struct {
+		File    string
+		Err     error
+		Results []TestResult
+	}
+)
+
+// FindTestFiles returns the Kiwi test files (those named *_test.kw) found
+// in paths, walking any directories recursively.
+func FindTestFiles(paths []string) ([]string, error) {
+	var files []string
+	for _, path := range paths {
+		err := filepath.Walk(path, func(p string, info os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+			if !info.IsDir() && strings.HasSuffix(p, "_test.kw") {
+				files = append(files, p)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	sort.Strings(files)
+	return files, nil
+}
+
+// TestFuncs returns the functions of prog whose names begin with "test", in
+// the order they are defined.
+func TestFuncs(prog *AstProgramNode) []*AstFuncDefNode {
+	var funcs []*AstFuncDefNode
+	for _, stmt := range prog.Stmts {
+		if fn, ok := stmt.(*AstFuncDefNode); ok && strings.HasPrefix(fn.Name, "test") {
+			funcs = append(funcs, fn)
+		}
+	}
+	return funcs
+}
+
+// RunTestSuite runs each test function of prog, which was read from file,
+// in a fresh runtime observed by tracers.
+func RunTestSuite(file string, prog *AstProgramNode, tracers ...Tracer) *TestSuite {
+	suite := &TestSuite{File: file}
+	for _, fn := range TestFuncs(prog) {
+		suite.Results = append(suite.Results, RunTest(prog, fn, tracers...))
+	}
+	return suite
+}
+
+// RunTest calls the test function fn of prog in a fresh runtime, recording
+// whether it returned normally.
+func RunTest(prog *AstProgramNode, fn *AstFuncDefNode, tracers ...Tracer) TestResult {
+	result := TestResult{Name: fn.Name, Pos: fn.Pos, Passed: true}
+
+	out := &bytes.Buffer{}
+	r := NewRuntime(&RuntimeEnv{
+		stdin:  strings.NewReader(""),
+		stdout: out,
+		stderr: out,
+		caps:   CapDefault,
+	})
+	for _, t := range tracers {
+		r.AddTracer(t)
+	}
+
+	call := &AstProgramNode{
+		Scope: prog.Scope,
+		Stmts: []AstNode{&AstFuncCallNode{Pos: fn.Pos, Name: fn.Name}},
+	}
+	start := time.Now()
+	err := r.Run(context.Background(), call)
+	result.Duration = time.Since(start)
+	result.Output = out.String()
+	if err != nil {
+		result.Passed = false
+		result.Pos = r.StopPos()
+		result.Message = err.Error()
+		var ae AssertionError
+		if errors.As(err, &ae) {
+			result.Failure = true
+			result.Message = ae.Message
+		}
+	}
+	return result
+}
+
+// Failed reports whether the suite could not be run or any of its tests
+// did not pass.
+func (s *TestSuite) Failed() bool {
+	if s.Err != nil {
+		return true
+	}
+	for _, r := range s.Results {
+		if !r.Passed {
+			return true
+		}
+	}
+	return false
+}
+
+// WriteTestText writes a human-readable report of suites, showing the
+// output of failed tests.
+func WriteTestText(w io.Writer, suites []*TestSuite) {
+	passed, failed := 0, 0
+	for _, s := range suites {
+		if s.Err != nil {
+			failed++
+			fmt.Fprintf(w, "FAIL %s: %v\n", s.File, s.Err)
+			continue
+		}
+		for _, r := range s.Results {
+			if r.Passed {
+				passed++
+				continue
+			}
+			failed++
+			fmt.Fprintf(w, "FAIL %s (%s:%d:%d)\n", r.Name, s.File, r.Pos.Line, r.Pos.Col)
+			fmt.Fprintf(w, "    %s\n", r.Message)
+			if r.Output != "" {
+				for _, line := range strings.Split(strings.TrimRight(r.Output, "\n"), "\n") {
+					fmt.Fprintf(w, "    | %s\n", line)
+				}
+			}
+		}
+	}
+	status := "PASS"
+	if failed > 0 {
+		status = "FAIL"
+	}
+	fmt.Fprintf(w, "%s: %d passed, %d failed\n", status, passed, failed)
+}
+
+// WriteTestTAP writes the results of suites in the Test Anything Protocol.
+func WriteTestTAP(w io.Writer, suites []*TestSuite) {
+	total := 0
+	for _, s := range suites {
+		if s.Err != nil {
+			total++
+		}
+		total += len(s.Results)
+	}
+
+	fmt.Fprintln(w, "TAP version 13")
+	fmt.Fprintf(w, "1..%d\n", total)
+	i := 0
+	for _, s := range suites {
+		if s.Err != nil {
+			i++
+			fmt.Fprintf(w, "not ok %d - %s\n", i, s.File)
+			fmt.Fprintln(w, "  ---")
+			fmt.Fprintf(w, "  message: %q\n", s.Err.Error())
+			fmt.Fprintln(w, "  ...")
+			continue
+		}
+		for _, r := range s.Results {
+			i++
+			if r.Passed {
+				fmt.Fprintf(w, "ok %d - %s: %s\n", i, s.File, r.Name)
+				continue
+			}
+			fmt.Fprintf(w, "not ok %d - %s: %s\n", i, s.File, r.Name)
+			fmt.Fprintln(w, "  ---")
+			fmt.Fprintf(w, "  message: %q\n", r.Message)
+			fmt.Fprintf(w, "  at: \"%s:%d:%d\"\n", s.File, r.Pos.Line, r.Pos.Col)
+			fmt.Fprintln(w, "  ...")
+		}
+	}
+}
+
+type (
+	junitSuites struct {
+		XMLName xml.Name     `xml:"testsuites"`
+		Suites  []junitSuite `xml:"testsuite"`
+	}
+
+	junitSuite struct {
+		Name     string      `xml:"name,attr"`
+		Tests    int         `xml:"tests,attr"`
+		Failures int         `xml:"failures,attr"`
+		Errors   int         `xml:"errors,attr"`
+		Time     string      `xml:"time,attr"`
+		Cases    []junitCase `xml:"testcase"`
+	}
+
+	junitCase struct {
+		Name      string        `xml:"name,attr"`
+		Classname string        `xml:"classname,attr"`
+		Time      string        `xml:"time,attr"`
+		Failure   *junitProblem `xml:"failure,omitempty"`
+		Error     *junitProblem `xml:"error,omitempty"`
+		SystemOut string        `xml:"system-out,omitempty"`
+	}
+
+	junitProblem struct {
+		Message string `xml:"message,attr"`
+		Text    string `xml:",chardata"`
+	}
+)
+
+// WriteTestJUnit writes the results of suites as JUnit XML.
+func WriteTestJUnit(w io.Writer, suites []*TestSuite) error {
+	doc := junitSuites{}
+	for _, s := range suites {
+		js := junitSuite{Name: s.File}
+		var total time.Duration
+		if s.Err != nil {
+			js.Tests, js.Errors = 1, 1
+			js.Cases = append(js.Cases, junitCase{
+				Name:      s.File,
+				Classname: s.File,
+				Error:     &junitProblem{Message: s.Err.Error()},
+			})
+		}
+		for _, r := range s.Results {
+			total += r.Duration
+			jc := junitCase{
+				Name:      r.Name,
+				Classname: s.File,
+				Time:      fmt.Sprintf("%.6f", r.Duration.Seconds()),
+				SystemOut: r.Output,
+			}
+			if !r.Passed {
+				problem := &junitProblem{
+					Message: r.Message,
+					Text:    fmt.Sprintf("%s:%d:%d: %s", s.File, r.Pos.Line, r.Pos.Col, r.Message),
+				}
+				if r.Failure {
+					jc.Failure = problem
+					js.Failures++
+				} else {
+					jc.Error = problem
+					js.Errors++
+				}
+			}
+			js.Tests++
+			js.Cases = append(js.Cases, jc)
+		}
+		js.Time = fmt.Sprintf("%.6f", total.Seconds())
+		doc.Suites = append(doc.Suites, js)
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(doc); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
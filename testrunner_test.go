@@ -0,0 +1,121 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const testSource = `func double n {
+  return n * 2
+}
+
+func test_pass {
+  assert_eq(double(2), 4)
+}
+
+func test_fail {
+  write("noise\n")
+  assert(double(1) = 3, "double is wrong")
+}
+
+func test_error {
+  x := y
+}
+
+func helper {
+  fail()
+}
+`
+
+func runTestSource(src string) *TestSuite {
+	prog, _ := newParser(src).Parse()
+	return RunTestSuite("double_test.kw", prog)
+}
+
+func TestTestRunner(t *testing.T) {
+	t.Parallel()
+
+	t.Run("Find test files", func(t *testing.T) {
+		dir, _ := ioutil.TempDir("", "kiwi")
+		defer os.RemoveAll(dir)
+		os.Mkdir(filepath.Join(dir, "sub"), 0755)
+		for _, name := range []string{"a_test.kw", "a.kw", "sub/b_test.kw"} {
+			ioutil.WriteFile(filepath.Join(dir, name), []byte(""), 0644)
+		}
+
+		files, err := FindTestFiles([]string{dir})
+		assert.Nil(t, err)
+		assert.Equal(t, []string{
+			filepath.Join(dir, "a_test.kw"),
+			filepath.Join(dir, "sub/b_test.kw"),
+		}, files)
+	})
+
+	t.Run("Run test functions", func(t *testing.T) {
+		suite := runTestSource(testSource)
+		assert.Equal(t, 3, len(suite.Results))
+		assert.True(t, suite.Failed())
+
+		pass := suite.Results[0]
+		assert.Equal(t, "test_pass", pass.Name)
+		assert.True(t, pass.Passed)
+
+		fail := suite.Results[1]
+		assert.False(t, fail.Passed)
+		assert.True(t, fail.Failure)
+		assert.Equal(t, "double is wrong", fail.Message)
+		assert.Equal(t, Pos{11, 3}, fail.Pos)
+		assert.Equal(t, "noise\n", fail.Output)
+
+		err := suite.Results[2]
+		assert.False(t, err.Passed)
+		assert.False(t, err.Failure)
+		assert.Equal(t, Pos{15, 3}, err.Pos)
+	})
+
+	t.Run("Run tests as the runtime does", func(t *testing.T) {
+		suite := runTestSource("func test_exit {\n  if true { exit(2) }\n}\nfunc test_after {\n  assert(true)\n}")
+		exit := suite.Results[0]
+		assert.False(t, exit.Passed)
+		assert.False(t, exit.Failure)
+		assert.Equal(t, "exit status 2", exit.Message)
+		assert.Equal(t, Pos{2, 13}, exit.Pos)
+		assert.True(t, suite.Results[1].Passed)
+	})
+
+	t.Run("Isolate test runtimes", func(t *testing.T) {
+		suite := runTestSource("func test_a {\n  x := 1\n}\nfunc test_b {\n  x := \"s\"\n}\n")
+		assert.False(t, suite.Failed())
+	})
+
+	t.Run("Write text", func(t *testing.T) {
+		buf := &bytes.Buffer{}
+		WriteTestText(buf, []*TestSuite{runTestSource(testSource)})
+		out := buf.String()
+		assert.Contains(t, out, "FAIL test_fail (double_test.kw:11:3)\n    double is wrong\n    | noise\n")
+		assert.True(t, strings.HasSuffix(out, "FAIL: 1 passed, 2 failed\n"))
+	})
+
+	t.Run("Write TAP", func(t *testing.T) {
+		buf := &bytes.Buffer{}
+		WriteTestTAP(buf, []*TestSuite{runTestSource(testSource)})
+		out := buf.String()
+		assert.True(t, strings.HasPrefix(out, "TAP version 13\n1..3\nok 1 - double_test.kw: test_pass\n"))
+		assert.Contains(t, out, "not ok 2 - double_test.kw: test_fail\n  ---\n  message: \"double is wrong\"\n  at: \"double_test.kw:11:3\"\n  ...\n")
+	})
+
+	t.Run("Write JUnit", func(t *testing.T) {
+		buf := &bytes.Buffer{}
+		assert.Nil(t, WriteTestJUnit(buf, []*TestSuite{runTestSource(testSource)}))
+		out := buf.String()
+		assert.Contains(t, out, `<testsuite name="double_test.kw" tests="3" failures="1" errors="1"`)
+		assert.Contains(t, out, `<failure message="double is wrong">double_test.kw:11:3: double is wrong</failure>`)
+		assert.Contains(t, out, `<error message="variable is not defined">`)
+	})
+}
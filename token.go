@@ -19,6 +19,11 @@ const (
 	TkModulo
 	mulopEnd
 
+	powopStart
+	// exponentiation operator
+	TkPower
+	powopEnd
+
 	cmpopStart
 	// comparision operators
 	TkEqual
@@ -49,9 +54,15 @@ const (
 	TkBool
 	TkIdentifier
 	TkNumber
+	TkDecimal
 	TkString
 	litEnd
 
+	// interpolated string segments
+	TkInterpStart
+	TkInterpMid
+	TkInterpEnd
+
 	TkAssign
 	TkLBrace
 	TkRBrace
@@ -61,6 +72,8 @@ const (
 	TkElse
 	TkLParen
 	TkRParen
+	TkLBracket
+	TkRBracket
 
 	// end of tokens
 	endTokens
@@ -79,6 +92,9 @@ func Precedence(t Token) int {
 	if t.IsMulOp() {
 		return 4
 	}
+	if t.IsPowOp() {
+		return 5
+	}
 	panic("token is not an operator")
 }
 
@@ -90,6 +106,10 @@ func (t Token) IsMulOp() bool {
 	return t > mulopStart && t < mulopEnd
 }
 
+func (t Token) IsPowOp() bool {
+	return t > powopStart && t < powopEnd
+}
+
 func (t Token) IsCmpOp() bool {
 	return t > cmpopStart && t < cmpopEnd
 }
@@ -99,7 +119,7 @@ func (t Token) IsLogOp() bool {
 }
 
 func (t Token) IsBinOp() bool {
-	return (t.IsAddOp() || t.IsMulOp() || t.IsCmpOp() || t.IsLogOp()) && t != TkNot
+	return (t.IsAddOp() || t.IsMulOp() || t.IsPowOp() || t.IsCmpOp() || t.IsLogOp()) && t != TkNot
 }
 
 func (t Token) IsUnaryOp() bool {
@@ -4,9 +4,9 @@ package main
 
 import "strconv"
 
-const _Token_name = "TkUnknownTkEOFaddopStartTkAddTkSubtractaddopEndmulopStartTkMultiplyTkDivideTkModulomulopEndcmpopStartTkEqualTkNotEqualTkGreaterTkGreaterEqTkLessTkLessEqcmpopEndlogopStartTkAndTkOrTkNotlogopEndstmtkwdStartTkIfTkFuncTkReturnTkWhilestmtkwdEndlitStartTkBoolTkIdentifierTkNumberTkStringlitEndTkAssignTkLBraceTkRBraceTkColonTkCommaTkCommentTkElseTkLParenTkRParenendTokens"
+const _Token_name = "TkUnknownTkEOFaddopStartTkAddTkSubtractaddopEndmulopStartTkMultiplyTkDivideTkModulomulopEndpowopStartTkPowerpowopEndcmpopStartTkEqualTkNotEqualTkGreaterTkGreaterEqTkLessTkLessEqcmpopEndlogopStartTkAndTkOrTkNotlogopEndstmtkwdStartTkIfTkFuncTkReturnTkWhilestmtkwdEndlitStartTkBoolTkIdentifierTkNumberTkDecimalTkStringlitEndTkInterpStartTkInterpMidTkInterpEndTkAssignTkLBraceTkRBraceTkColonTkCommaTkCommentTkElseTkLParenTkRParenTkLBracketTkRBracketendTokens"
 
-var _Token_index = [...]uint16{0, 9, 14, 24, 29, 39, 47, 57, 67, 75, 83, 91, 101, 108, 118, 127, 138, 144, 152, 160, 170, 175, 179, 184, 192, 204, 208, 214, 222, 229, 239, 247, 253, 265, 273, 281, 287, 295, 303, 311, 318, 325, 334, 340, 348, 356, 365}
+var _Token_index = [...]uint16{0, 9, 14, 24, 29, 39, 47, 57, 67, 75, 83, 91, 101, 108, 116, 126, 133, 143, 152, 163, 169, 177, 185, 195, 200, 204, 209, 217, 229, 233, 239, 247, 254, 264, 272, 278, 290, 298, 307, 315, 321, 334, 345, 356, 364, 372, 380, 387, 394, 403, 409, 417, 425, 435, 445, 454}
 
 func (i Token) String() string {
 	if i >= Token(len(_Token_index)-1) {
@@ -31,6 +31,17 @@ func TestTokens(t *testing.T) {
 		}
 	})
 
+	t.Run("Test IsPowOp", func(t *testing.T) {
+		for i := 0; i < int(endTokens); i++ {
+			tkn := Token(i)
+			if tkn == TkPower {
+				assert.True(t, tkn.IsPowOp(), tkn.String())
+			} else {
+				assert.False(t, tkn.IsPowOp(), tkn.String())
+			}
+		}
+	})
+
 	t.Run("Test IsCmpOp", func(t *testing.T) {
 		for i := 0; i < int(endTokens); i++ {
 			tkn := Token(i)
@@ -60,6 +71,7 @@ func TestTokens(t *testing.T) {
 			tkn := Token(i)
 			if tkn == TkAdd || tkn == TkSubtract ||
 				tkn == TkMultiply || tkn == TkDivide || tkn == TkModulo ||
+				tkn == TkPower ||
 				tkn == TkEqual || tkn == TkNotEqual ||
 				tkn == TkLess || tkn == TkLessEq ||
 				tkn == TkGreater || tkn == TkGreaterEq ||
@@ -97,7 +109,7 @@ func TestTokens(t *testing.T) {
 		for i := 0; i < int(endTokens); i++ {
 			tkn := Token(i)
 			if tkn == TkIdentifier || tkn == TkBool || tkn == TkNumber ||
-				tkn == TkString {
+				tkn == TkDecimal || tkn == TkString {
 				assert.True(t, tkn.IsLiteral(), tkn.String())
 			} else {
 				assert.False(t, tkn.IsLiteral(), tkn.String())
@@ -107,6 +119,7 @@ func TestTokens(t *testing.T) {
 
 	t.Run("Test precedence", func(t *testing.T) {
 		tokens := []struct{ t1, t2 Token }{
+			{TkPower, TkMultiply},
 			{TkMultiply, TkAdd},
 			{TkAdd, TkLess},
 			{TkLess, TkAnd},
@@ -125,40 +138,47 @@ func TestTokens(t *testing.T) {
 
 	t.Run("Test token to string", func(t *testing.T) {
 		tokens := map[Token]string{
-			TkUnknown:    "TkUnknown",
-			TkEOF:        "TkEOF",
-			TkAdd:        "TkAdd",
-			TkSubtract:   "TkSubtract",
-			TkMultiply:   "TkMultiply",
-			TkDivide:     "TkDivide",
-			TkModulo:     "TkModulo",
-			TkEqual:      "TkEqual",
-			TkNotEqual:   "TkNotEqual",
-			TkGreater:    "TkGreater",
-			TkGreaterEq:  "TkGreaterEq",
-			TkLess:       "TkLess",
-			TkLessEq:     "TkLessEq",
-			TkAnd:        "TkAnd",
-			TkOr:         "TkOr",
-			TkNot:        "TkNot",
-			TkIf:         "TkIf",
-			TkFunc:       "TkFunc",
-			TkReturn:     "TkReturn",
-			TkWhile:      "TkWhile",
-			TkBool:       "TkBool",
-			TkIdentifier: "TkIdentifier",
-			TkNumber:     "TkNumber",
-			TkString:     "TkString",
-			TkAssign:     "TkAssign",
-			TkLBrace:     "TkLBrace",
-			TkRBrace:     "TkRBrace",
-			TkColon:      "TkColon",
-			TkComma:      "TkComma",
-			TkComment:    "TkComment",
-			TkElse:       "TkElse",
-			TkLParen:     "TkLParen",
-			TkRParen:     "TkRParen",
-			Token(255):   "Token(255)",
+			TkUnknown:     "TkUnknown",
+			TkEOF:         "TkEOF",
+			TkAdd:         "TkAdd",
+			TkSubtract:    "TkSubtract",
+			TkMultiply:    "TkMultiply",
+			TkDivide:      "TkDivide",
+			TkModulo:      "TkModulo",
+			TkPower:       "TkPower",
+			TkEqual:       "TkEqual",
+			TkNotEqual:    "TkNotEqual",
+			TkGreater:     "TkGreater",
+			TkGreaterEq:   "TkGreaterEq",
+			TkLess:        "TkLess",
+			TkLessEq:      "TkLessEq",
+			TkAnd:         "TkAnd",
+			TkOr:          "TkOr",
+			TkNot:         "TkNot",
+			TkIf:          "TkIf",
+			TkFunc:        "TkFunc",
+			TkReturn:      "TkReturn",
+			TkWhile:       "TkWhile",
+			TkBool:        "TkBool",
+			TkIdentifier:  "TkIdentifier",
+			TkNumber:      "TkNumber",
+			TkDecimal:     "TkDecimal",
+			TkString:      "TkString",
+			TkInterpStart: "TkInterpStart",
+			TkInterpMid:   "TkInterpMid",
+			TkInterpEnd:   "TkInterpEnd",
+			TkAssign:      "TkAssign",
+			TkLBrace:      "TkLBrace",
+			TkRBrace:      "TkRBrace",
+			TkColon:       "TkColon",
+			TkComma:       "TkComma",
+			TkComment:     "TkComment",
+			TkElse:        "TkElse",
+			TkLParen:      "TkLParen",
+			TkRParen:      "TkRParen",
+			TkLBracket:    "TkLBracket",
+			TkRBracket:    "TkRBracket",
+			Token(255):    "Token(255)",
 		}
 
 		for token, str := range tokens {
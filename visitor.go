@@ -6,6 +6,7 @@ type Visitor interface {
 	VisitAssignNode(*AstAssignNode)
 	VisitBoolNode(*AstBoolNode)
 	VisitCastNode(*AstCastNode)
+	VisitDecimalNode(*AstDecimalNode)
 	VisitDivideNode(*AstDivideNode)
 	VisitEqualNode(*AstEqualNode)
 	VisitFuncCallNode(*AstFuncCallNode)
@@ -13,6 +14,8 @@ type Visitor interface {
 	VisitGreaterEqualNode(*AstGreaterEqualNode)
 	VisitGreaterNode(*AstGreaterNode)
 	VisitIfNode(*AstIfNode)
+	VisitIndexNode(*AstIndexNode)
+	VisitInterpNode(*AstInterpNode)
 	VisitLessEqualNode(*AstLessEqualNode)
 	VisitLessNode(*AstLessNode)
 	VisitModuloNode(*AstModuloNode)
@@ -23,8 +26,10 @@ type Visitor interface {
 	VisitNumberNode(*AstNumberNode)
 	VisitOrNode(*AstOrNode)
 	VisitPositiveNode(*AstPositiveNode)
+	VisitPowerNode(*AstPowerNode)
 	VisitProgramNode(*AstProgramNode)
 	VisitReturnNode(*AstReturnNode)
+	VisitSliceNode(*AstSliceNode)
 	VisitStringNode(*AstStringNode)
 	VisitSubtractNode(*AstSubtractNode)
 	VisitVariableNode(*AstVariableNode)